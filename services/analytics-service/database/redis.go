@@ -1,14 +0,0 @@
-package database
-
-import (
-	"github.com/redis/go-redis/v9"
-)
-
-func NewRedisClient(redisURL string) *redis.Client {
-	opts, err := redis.ParseURL(redisURL)
-	if err != nil {
-		panic(err)
-	}
-
-	return redis.NewClient(opts)
-}
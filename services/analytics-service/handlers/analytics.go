@@ -1,13 +1,14 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/margwa/analytics-service/models"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -26,13 +27,7 @@ func NewAnalyticsHandler(db *pgxpool.Pool, redis *redis.Client) *AnalyticsHandle
 func (h *AnalyticsHandler) GetDriverStats(c *gin.Context) {
 	driverID, err := uuid.Parse(c.Param("driver_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "INVALID_DRIVER_ID",
-				Message: "Invalid driver ID format",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_DRIVER_ID", "Invalid driver ID format", nil))
 		return
 	}
 
@@ -62,7 +57,7 @@ func (h *AnalyticsHandler) GetDriverStats(c *gin.Context) {
 	`
 
 	var stats models.DriverStats
-	err = h.db.QueryRow(context.Background(), query, driverID).Scan(
+	err = h.db.QueryRow(tracing.Context(c), query, driverID).Scan(
 		&stats.DriverID,
 		&stats.TotalTrips,
 		&stats.CompletedTrips,
@@ -75,33 +70,17 @@ func (h *AnalyticsHandler) GetDriverStats(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to retrieve driver statistics",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to retrieve driver statistics", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    stats,
-		Message: "Driver statistics retrieved",
-	})
+	c.JSON(http.StatusOK, response.Success(stats, "Driver statistics retrieved"))
 }
 
 func (h *AnalyticsHandler) GetDriverEarnings(c *gin.Context) {
 	driverID, err := uuid.Parse(c.Param("driver_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "INVALID_DRIVER_ID",
-				Message: "Invalid driver ID format",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_DRIVER_ID", "Invalid driver ID format", nil))
 		return
 	}
 
@@ -125,15 +104,9 @@ func (h *AnalyticsHandler) GetDriverEarnings(c *gin.Context) {
 		ORDER BY DATE(b.created_at) DESC
 	`
 
-	rows, err := h.db.Query(context.Background(), query, driverID, startDate, endDate)
+	rows, err := h.db.Query(tracing.Context(c), query, driverID, startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to retrieve earnings data",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to retrieve earnings data", nil))
 		return
 	}
 	defer rows.Close()
@@ -154,23 +127,13 @@ func (h *AnalyticsHandler) GetDriverEarnings(c *gin.Context) {
 		earnings = append(earnings, earning)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    earnings,
-		Message: "Earnings data retrieved",
-	})
+	c.JSON(http.StatusOK, response.Success(earnings, "Earnings data retrieved"))
 }
 
 func (h *AnalyticsHandler) GetTripAnalytics(c *gin.Context) {
 	tripID, err := uuid.Parse(c.Param("trip_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "INVALID_TRIP_ID",
-				Message: "Invalid trip ID format",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_TRIP_ID", "Invalid trip ID format", nil))
 		return
 	}
 
@@ -195,7 +158,7 @@ func (h *AnalyticsHandler) GetTripAnalytics(c *gin.Context) {
 	`
 
 	var analytics models.TripAnalytics
-	err = h.db.QueryRow(context.Background(), query, tripID).Scan(
+	err = h.db.QueryRow(tracing.Context(c), query, tripID).Scan(
 		&analytics.TripID,
 		&analytics.DistanceKm,
 		&analytics.DurationMinutes,
@@ -206,21 +169,11 @@ func (h *AnalyticsHandler) GetTripAnalytics(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "NOT_FOUND",
-				Message: "Trip not found",
-			},
-		})
+		c.JSON(http.StatusNotFound, response.Error("NOT_FOUND", "Trip not found", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    analytics,
-		Message: "Trip analytics retrieved",
-	})
+	c.JSON(http.StatusOK, response.Success(analytics, "Trip analytics retrieved"))
 }
 
 func (h *AnalyticsHandler) GetPlatformStats(c *gin.Context) {
@@ -244,7 +197,7 @@ func (h *AnalyticsHandler) GetPlatformStats(c *gin.Context) {
 	`
 
 	var stats models.PlatformStats
-	err := h.db.QueryRow(context.Background(), query).Scan(
+	err := h.db.QueryRow(tracing.Context(c), query).Scan(
 		&stats.TotalUsers,
 		&stats.TotalDrivers,
 		&stats.ActiveDrivers,
@@ -254,45 +207,27 @@ func (h *AnalyticsHandler) GetPlatformStats(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to retrieve platform statistics",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to retrieve platform statistics", nil))
 		return
 	}
 
 	stats.PeakHours = []int{}
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    stats,
-		Message: "Platform statistics retrieved",
-	})
+	c.JSON(http.StatusOK, response.Success(stats, "Platform statistics retrieved"))
 }
 
 func (h *AnalyticsHandler) GenerateReport(c *gin.Context) {
 	var request models.ReportRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "INVALID_REQUEST",
-				Message: "Invalid request body",
-			},
-		})
+		reportJobs.WithLabelValues("invalid").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_REQUEST", "Invalid request body", nil))
 		return
 	}
 
 	reportID := uuid.New()
 	reportURL := "/reports/" + reportID.String() + ".pdf"
+	reportJobs.WithLabelValues("generated").Inc()
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    reportURL,
-		Message: "Report generated successfully",
-	})
+	c.JSON(http.StatusOK, response.Success(reportURL, "Report generated successfully"))
 }
 
 func (h *AnalyticsHandler) GetRouteTrends(c *gin.Context) {
@@ -316,15 +251,9 @@ func (h *AnalyticsHandler) GetRouteTrends(c *gin.Context) {
 		LIMIT 20
 	`
 
-	rows, err := h.db.Query(context.Background(), query)
+	rows, err := h.db.Query(tracing.Context(c), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to retrieve route trends",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to retrieve route trends", nil))
 		return
 	}
 	defer rows.Close()
@@ -346,9 +275,5 @@ func (h *AnalyticsHandler) GetRouteTrends(c *gin.Context) {
 		trends = append(trends, trend)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    trends,
-		Message: "Route trends retrieved",
-	})
+	c.JSON(http.StatusOK, response.Success(trends, "Route trends retrieved"))
 }
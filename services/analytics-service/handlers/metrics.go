@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Domain metrics, served on /metrics next to the shared HTTP and pool metrics
+
+// reportJobs is labelled by outcome only; report_type comes straight from the
+// request body and would let clients create unbounded series.
+var reportJobs = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "margwa_report_jobs_total",
+	Help: "Report generation requests, by outcome (generated, invalid).",
+}, []string{"status"})
@@ -2,48 +2,109 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"github.com/margwa/analytics-service/config"
-	"github.com/margwa/analytics-service/database"
 	"github.com/margwa/analytics-service/handlers"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/health"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/metrics"
+	"github.com/margwa/shared/go/migrate"
+	"github.com/margwa/shared/go/postgres"
+	"github.com/margwa/shared/go/redisclient"
+	"github.com/margwa/shared/go/server"
+	"github.com/margwa/shared/go/tracing"
 )
 
+// schemaVersion is the newest shared/go/migrate migration this service
+// depends on (impersonation_audit)
+const schemaVersion = 13
+
 func main() {
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	runMigrations := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load("../../.env"); err != nil {
-		log.Println("No .env file found, using environment variables")
-	}
+	env.Load("../../.env")
 
 	// Load configuration
-	cfg := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if *printConfig {
+		env.Print(os.Stdout, cfg)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
+
+	// JSON logs with request IDs; the standard log package writes through it too
+	logging.Setup("analytics-service", cfg.LogLevel)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "analytics-service", tracing.Config{
+		Exporter:    cfg.TraceExporter,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
 
 	// Initialize database
-	db, err := database.NewPostgresConnection(cfg.DatabaseURL)
+	db, err := postgres.Connect(context.Background(), postgres.Config{URL: cfg.DatabaseURL})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
+	// --migrate applies pending migrations and exits; otherwise refuse to
+	// start against a schema older than this build needs
+	if *runMigrations {
+		if err := migrate.Run(context.Background(), db); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := migrate.Require(context.Background(), db, schemaVersion); err != nil {
+		log.Fatalf("Incompatible database schema: %v", err)
+	}
+
 	// Initialize Redis
-	redisClient := database.NewRedisClient(cfg.RedisURL)
+	redisClient, err := redisclient.New(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to configure Redis: %v", err)
+	}
 	defer redisClient.Close()
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		log.Printf("⚠️  Redis unavailable at startup: %v", err)
+	}
+
+	// Prometheus metrics
+	m := metrics.New("analytics-service")
+	m.WatchPostgres(db)
+	m.WatchRedis(redisClient)
 
 	// Setup Gin router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(tracing.Middleware("analytics-service"))
+	router.Use(logging.Middleware())
+	router.Use(m.Middleware())
 
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -53,48 +114,59 @@ func main() {
 		c.Next()
 	})
 
+	router.Use(auth.AuditImpersonation(db, "analytics-service"))
+
 	// Health check
 	router.GET("/health", handlers.HealthCheck)
+	router.GET("/metrics", metrics.Handler())
+
+	// Liveness and readiness probes
+	probes := health.New("analytics-service")
+	probes.Add("postgres", 2*time.Second, health.Postgres(db))
+	probes.Add("redis", 2*time.Second, health.Redis(redisClient))
+	router.GET("/livez", probes.Live)
+	router.GET("/readyz", probes.Ready)
+
+	// Access tokens verify against auth-service's JWKS, plus HS256 while migrating
+	var jwks *auth.JWKSCache
+	if cfg.JWKSURL != "" {
+		jwks = auth.NewJWKSCache(cfg.JWKSURL, 10*time.Minute)
+	}
+	hmacSecret := ""
+	if cfg.JWTAcceptHS256 {
+		hmacSecret = cfg.JWTSecret
+	}
 
 	// Analytics routes
 	analyticsHandler := handlers.NewAnalyticsHandler(db, redisClient)
-	router.GET("/analytics/driver/:driver_id/stats", analyticsHandler.GetDriverStats)
-	router.GET("/analytics/driver/:driver_id/earnings", analyticsHandler.GetDriverEarnings)
-	router.GET("/analytics/trip/:trip_id", analyticsHandler.GetTripAnalytics)
-	router.GET("/analytics/platform/stats", analyticsHandler.GetPlatformStats)
-	router.POST("/analytics/reports/generate", analyticsHandler.GenerateReport)
-	router.GET("/analytics/trends/routes", analyticsHandler.GetRouteTrends)
-
-	// Start server
-	port := cfg.Port
-	if port == "" {
-		port = "8085"
-	}
-
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+	analytics := router.Group("/analytics")
+	analytics.Use(auth.Middleware(auth.Keyfunc(hmacSecret, jwks), nil))
+	{
+		analytics.GET("/driver/:driver_id/stats", analyticsHandler.GetDriverStats)
+		analytics.GET("/driver/:driver_id/earnings", analyticsHandler.GetDriverEarnings)
+		analytics.GET("/trip/:trip_id", analyticsHandler.GetTripAnalytics)
+		analytics.GET("/platform/stats", auth.RequirePermission("analytics:read"), analyticsHandler.GetPlatformStats)
+		analytics.POST("/reports/generate", auth.RequirePermission("analytics:read"), analyticsHandler.GenerateReport)
+		analytics.GET("/trends/routes", analyticsHandler.GetRouteTrends)
 	}
 
-	// Graceful shutdown
-	go func() {
-		log.Printf("🚀 Analytics Service listening on :%s", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
-
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// Start server; on SIGINT/SIGTERM readiness fails first, then in-flight
+	// requests finish before the listener closes
+	srv := server.New(router, server.Config{
+		Port:              cfg.Port,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		DrainDelay:        cfg.ShutdownDrainDelay,
+		ShutdownTimeout:   cfg.ShutdownTimeout,
+	})
+	srv.OnDrain(probes.Drain)
+	srv.OnShutdown("tracing", shutdownTracing)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	log.Printf("🚀 Analytics Service listening on :%s", cfg.Port)
+	if err := srv.Run(); err != nil {
+		log.Fatalf("Server stopped: %v", err)
 	}
 
 	log.Println("Server exited")
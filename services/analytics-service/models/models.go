@@ -62,16 +62,3 @@ type ReportRequest struct {
 	EndDate    string     `json:"end_date"`
 	DriverID   *uuid.UUID `json:"driver_id,omitempty"`
 }
-
-type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   *APIError   `json:"error,omitempty"`
-	Message string      `json:"message,omitempty"`
-}
-
-type APIError struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
-}
@@ -1,6 +1,6 @@
 import { Request, Response, NextFunction } from 'express';
-import jwt from 'jsonwebtoken';
 import { errorResponse } from '../../../../shared/utils';
+import { verifyAccessToken } from '../../../../shared/utils/jwt';
 import { ErrorCodes, JWTPayload } from '../../../../shared/types';
 
 // Extend Express Request type
@@ -30,14 +30,7 @@ export const authenticate = async (
         }
 
         const token = authHeader.substring(7);
-        const secret = process.env.JWT_SECRET;
-
-        if (!secret) {
-            throw new Error('JWT_SECRET not configured');
-        }
-
-        const decoded = jwt.verify(token, secret) as JWTPayload;
-        req.user = decoded;
+        req.user = await verifyAccessToken(token);
 
         next();
     } catch (error: any) {
@@ -0,0 +1,276 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"margwa/auth-service/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/auth"
+)
+
+// Purger erases accounts whose deletion grace period has passed. The users
+// row is kept, anonymized, so bookings, payments and earnings (which must be
+// retained as financial records) still reference a valid user. Stored files
+// are queued in storage_deletions and removed through storage-service.
+type Purger struct {
+	db       *pgxpool.Pool
+	tokens   *auth.Denylist
+	files    *storage.Client
+	interval time.Duration
+}
+
+func NewPurger(db *pgxpool.Pool, tokens *auth.Denylist, files *storage.Client, interval time.Duration) *Purger {
+	return &Purger{db: db, tokens: tokens, files: files, interval: interval}
+}
+
+// Run purges due accounts every interval until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := p.PurgeDue(ctx); err != nil {
+			slog.ErrorContext(ctx, "Account purge failed", "error", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "Purged deleted accounts", "count", n)
+		}
+		if n, err := p.DeleteQueuedFiles(ctx); err != nil {
+			slog.ErrorContext(ctx, "Stored file deletion failed", "error", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "Deleted stored files of purged accounts", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeDue erases every account whose scheduled deletion time has passed
+func (p *Purger) PurgeDue(ctx context.Context) (int, error) {
+	rows, err := p.db.Query(ctx,
+		`SELECT id FROM users
+		 WHERE deletion_scheduled_at <= NOW() AND deleted_at IS NULL`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var due []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range due {
+		ok, err := p.purge(ctx, id)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error purging account", "user_id", id.String(), "error", err)
+			continue
+		}
+		if ok {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// driverVehicles selects the ids of the user's vehicles
+const driverVehicles = `SELECT v.id FROM vehicles v JOIN driver_profiles d ON d.id = v.driver_id WHERE d.user_id = $1`
+
+// purgeStatements anonymize or delete a user's personal data. Bookings,
+// payments and earnings are left untouched. The first statement queues every
+// stored file the later ones unlink.
+var purgeStatements = []string{
+	`INSERT INTO storage_deletions (url)
+	 SELECT DISTINCT url FROM (
+	   SELECT unnest(ARRAY[profile_image_url, avatar_url]) AS url FROM users WHERE id = $1
+	   UNION ALL
+	   SELECT license_image_url FROM driver_profiles WHERE user_id = $1
+	   UNION ALL
+	   SELECT unnest(ARRAY[rc_image_url, insurance_image_url, puc_image_url, permit_image_url])
+	   FROM vehicles WHERE id IN (` + driverVehicles + `)
+	   UNION ALL
+	   SELECT unnest(ARRAY[document_url, thumbnail_url])
+	   FROM driver_documents WHERE driver_id IN (SELECT id FROM driver_profiles WHERE user_id = $1)
+	   UNION ALL
+	   SELECT unnest(ARRAY[url, thumbnail_url]) FROM uploaded_files WHERE user_id = $1
+	 ) files
+	 WHERE url IS NOT NULL AND url <> ''`,
+	`UPDATE users SET
+	   phone_number = 'del_' || substr(md5(id::text), 1, 16),
+	   full_name = NULL,
+	   email = NULL,
+	   email_verified_at = NULL,
+	   profile_image_url = NULL,
+	   avatar_url = NULL,
+	   dob = NULL,
+	   gender = NULL,
+	   is_profile_complete = false,
+	   is_verified = false,
+	   is_active = false,
+	   deleted_at = NOW(),
+	   updated_at = NOW()
+	 WHERE id = $1`,
+	`DELETE FROM sessions WHERE user_id = $1`,
+	`DELETE FROM otp_verifications WHERE user_id = $1`,
+	`DELETE FROM notifications WHERE user_id = $1`,
+	`DELETE FROM auth_events WHERE user_id = $1`,
+	`DELETE FROM user_totp WHERE user_id = $1`,
+	`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+	`DELETE FROM uploaded_files WHERE user_id = $1`,
+	`UPDATE messages SET message_text = NULL WHERE sender_id = $1`,
+	`UPDATE reviews SET review_text = NULL WHERE reviewer_id = $1`,
+	`UPDATE driver_profiles SET
+	   license_number = NULL,
+	   license_expiry = NULL,
+	   license_image_url = NULL,
+	   current_latitude = NULL,
+	   current_longitude = NULL,
+	   is_online = false,
+	   updated_at = NOW()
+	 WHERE user_id = $1`,
+	`UPDATE vehicles SET
+	   rc_number = NULL,
+	   rc_image_url = NULL,
+	   insurance_number = NULL,
+	   insurance_image_url = NULL,
+	   puc_number = NULL,
+	   puc_image_url = NULL,
+	   permit_number = NULL,
+	   permit_image_url = NULL,
+	   is_active = false,
+	   updated_at = NOW()
+	 WHERE id IN (` + driverVehicles + `)`,
+	`DELETE FROM driver_documents WHERE driver_id IN (SELECT id FROM driver_profiles WHERE user_id = $1)`,
+}
+
+// purge erases one account in a transaction. It reports false if the
+// deletion was cancelled after the account was selected.
+func (p *Purger) purge(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user and make sure the deletion was not cancelled meanwhile
+	tag, err := tx.Exec(ctx,
+		`SELECT 1 FROM users
+		 WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at <= NOW()
+		 FOR UPDATE`,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	for i, stmt := range purgeStatements {
+		if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+			return false, fmt.Errorf("purge step %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	if err := p.tokens.RevokeUser(ctx, userID.String()); err != nil {
+		slog.ErrorContext(ctx, "Error denylisting tokens for purged account", "user_id", userID.String(), "error", err)
+	}
+	return true, nil
+}
+
+// fileDeletionBatch bounds the URLs sent to storage-service in one request
+const fileDeletionBatch = 100
+
+// DeleteQueuedFiles removes the queued files that are due from object
+// storage. Files storage-service could not delete are retried with a backoff
+// that doubles from one minute up to a day.
+func (p *Purger) DeleteQueuedFiles(ctx context.Context) (int, error) {
+	rows, err := p.db.Query(ctx,
+		`SELECT id, url FROM storage_deletions
+		 WHERE next_attempt_at <= NOW()
+		 ORDER BY id
+		 LIMIT $1`,
+		fileDeletionBatch,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := map[string][]int64{}
+	var urls []string
+	for rows.Next() {
+		var id int64
+		var url string
+		if err := rows.Scan(&id, &url); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if _, ok := ids[url]; !ok {
+			urls = append(urls, url)
+		}
+		ids[url] = append(ids[url], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	failed, deleteErr := p.files.DeleteObjects(ctx, urls)
+	if deleteErr != nil {
+		// Nothing is known to be deleted, so every row is retried
+		failed = make(map[string]string, len(urls))
+		for _, url := range urls {
+			failed[url] = deleteErr.Error()
+		}
+	}
+
+	var done []int64
+	for _, url := range urls {
+		if msg, ok := failed[url]; ok {
+			if _, err := p.db.Exec(ctx,
+				`UPDATE storage_deletions SET
+				   attempts = attempts + 1,
+				   last_error = $2,
+				   next_attempt_at = NOW() + LEAST(POWER(2, attempts), 1440) * INTERVAL '1 minute'
+				 WHERE id = ANY($1)`,
+				ids[url], msg,
+			); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		done = append(done, ids[url]...)
+	}
+
+	if len(done) > 0 {
+		if _, err := p.db.Exec(ctx, "DELETE FROM storage_deletions WHERE id = ANY($1)", done); err != nil {
+			return 0, err
+		}
+	}
+	return len(done), deleteErr
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/logging"
+)
+
+// Event types recorded in auth_events
+const (
+	EventRegister                 = "register"
+	EventRoleUpgrade              = "role_upgrade"
+	EventOTPSent                  = "otp_sent"
+	EventOTPFailed                = "otp_failed"
+	EventLogin                    = "login"
+	EventTokenRefresh             = "token_refresh"
+	EventTokenReuse               = "token_reuse"
+	EventLogout                   = "logout"
+	EventSessionRevoked           = "session_revoked"
+	EventProfileChange            = "profile_change"
+	EventPhoneChange              = "phone_change"
+	EventEmailVerified            = "email_verified"
+	EventAccountDeletion          = "account_deletion_scheduled"
+	EventAccountDeletionCancelled = "account_deletion_cancelled"
+	EventPersonaSwitch            = "persona_switch"
+	EventMFAEnrolled              = "mfa_enrolled"
+	EventMFAVerified              = "mfa_verified"
+	EventMFADisabled              = "mfa_disabled"
+	EventRecoveryCodesRegenerated = "mfa_recovery_codes_regenerated"
+	EventRoleGranted              = "role_granted"
+	EventRoleRevoked              = "role_revoked"
+)
+
+// Event is one security-relevant action on an account
+type Event struct {
+	UserID    string
+	Type      string
+	IPAddress string
+	DeviceID  string
+	UserAgent string
+	Metadata  map[string]interface{}
+}
+
+// AuditLogger writes auth events to the auth_events table
+type AuditLogger struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditLogger(db *pgxpool.Pool) *AuditLogger {
+	return &AuditLogger{db: db}
+}
+
+// Log records the event. Failures are logged and never fail the request
+// that triggered the event.
+func (a *AuditLogger) Log(ctx context.Context, e Event) {
+	var metadata []byte
+	if len(e.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(e.Metadata); err != nil {
+			logging.Errorf(ctx, "Error encoding %s audit metadata: %v", e.Type, err)
+		}
+	}
+
+	_, err := a.db.Exec(ctx,
+		`INSERT INTO auth_events (user_id, event_type, ip_address, device_id, user_agent, metadata)
+		 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6)`,
+		e.UserID, e.Type, e.IPAddress, e.DeviceID, e.UserAgent, metadata,
+	)
+	if err != nil {
+		logging.Errorf(ctx, "Error recording %s event for user %s: %v", e.Type, e.UserID, err)
+	}
+}
@@ -1,23 +0,0 @@
-package database
-
-import (
-	"context"
-
-	"github.com/redis/go-redis/v9"
-)
-
-func NewRedisClient(redisURL string) *redis.Client {
-	opts, err := redis.ParseURL(redisURL)
-	if err != nil {
-		panic(err)
-	}
-
-	client := redis.NewClient(opts)
-
-	// Test connection
-	if err := client.Ping(context.Background()).Err(); err != nil {
-		panic(err)
-	}
-
-	return client
-}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"margwa/auth-service/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// exportQuery gathers everything stored about a user into one JSON document.
+// Columns are listed explicitly so that a column added to users later is not
+// exported until someone decides it belongs in the archive. Refresh and FCM
+// tokens are left out of the sessions.
+const exportQuery = `
+SELECT json_build_object(
+	'user', (
+		SELECT row_to_json(u) FROM (
+			SELECT id, phone_number, phone_country_code, full_name, email, email_verified_at,
+			       profile_image_url, avatar_url, dob, gender, is_profile_complete, user_type,
+			       is_verified, is_active, language_preference, created_at, updated_at,
+			       last_login_at, deletion_scheduled_at
+			FROM users WHERE id = $1
+		) u),
+	'sessions', COALESCE((
+		SELECT json_agg(s) FROM (
+			SELECT id, device_id, device_type, ip_address, created_at, last_used_at, expires_at
+			FROM sessions WHERE user_id = $1
+		) s), '[]'::json),
+	'bookings', COALESCE((
+		SELECT json_agg(b) FROM bookings b
+		WHERE b.client_id = $1
+		   OR b.driver_id IN (SELECT id FROM driver_profiles WHERE user_id = $1)
+	), '[]'::json),
+	'payments', COALESCE((SELECT json_agg(p) FROM payments p WHERE p.payer_id = $1), '[]'::json),
+	'reviews', COALESCE((
+		SELECT json_agg(r) FROM reviews r WHERE r.reviewer_id = $1 OR r.reviewee_id = $1
+	), '[]'::json),
+	'messages', COALESCE((
+		SELECT json_agg(m) FROM messages m WHERE m.sender_id = $1 OR m.receiver_id = $1
+	), '[]'::json)
+)`
+
+// ExportAccount returns a JSON archive of the user's data
+func (h *AuthHandler) ExportAccount(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	var archive json.RawMessage
+	err := h.db.QueryRow(tracing.Context(c), exportQuery, userID).Scan(&archive)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error exporting account %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to export account data", nil))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="margwa-export-%s.json"`, userID))
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"exportedAt": time.Now().Format(time.RFC3339),
+		"archive":    archive,
+	}, "Account data exported successfully"))
+}
+
+// DeleteAccount schedules the account for erasure after the grace period and
+// signs out every device. Logging in again and calling
+// CancelAccountDeletion before the deadline keeps the account.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("userId")
+	ctx := tracing.Context(c)
+
+	scheduledAt := time.Now().Add(h.config.AccountDeletionGracePeriod)
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to schedule account deletion", nil))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx,
+		`UPDATE users
+		 SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $1), updated_at = NOW()
+		 WHERE id = $2 AND deleted_at IS NULL
+		 RETURNING deletion_scheduled_at`,
+		scheduledAt, userID,
+	).Scan(&scheduledAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		logging.Errorf(ctx, "Error revoking sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to schedule account deletion", nil))
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.Errorf(ctx, "Error committing account deletion: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to schedule account deletion", nil))
+		return
+	}
+
+	if err := h.tokens.RevokeUser(ctx, userID); err != nil {
+		logging.Errorf(ctx, "Error denylisting tokens for %s: %v", userID, err)
+	}
+
+	h.recordEvent(c, userID, audit.EventAccountDeletion, nil, map[string]interface{}{
+		"deletionScheduledAt": scheduledAt,
+	})
+
+	c.JSON(http.StatusAccepted, response.Success(gin.H{
+		"deletionScheduledAt": scheduledAt,
+	}, "Account scheduled for deletion. Log in before this date to cancel."))
+}
+
+// CancelAccountDeletion keeps an account that is still in its grace period
+func (h *AuthHandler) CancelAccountDeletion(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	tag, err := h.db.Exec(tracing.Context(c),
+		`UPDATE users SET deletion_scheduled_at = NULL, updated_at = NOW()
+		 WHERE id = $1 AND deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error cancelling account deletion: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to cancel account deletion", nil))
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, response.Error("DELETION_NOT_SCHEDULED", "Account is not scheduled for deletion", nil))
+		return
+	}
+
+	h.recordEvent(c, userID, audit.EventAccountDeletionCancelled, nil, nil)
+
+	c.JSON(http.StatusOK, response.Success(nil, "Account deletion cancelled"))
+}
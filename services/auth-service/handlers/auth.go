@@ -2,48 +2,143 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
+	"margwa/auth-service/audit"
 	"margwa/auth-service/config"
+	"margwa/auth-service/keys"
+	"margwa/auth-service/mail"
 	"margwa/auth-service/models"
+	"margwa/auth-service/notify"
+	"margwa/auth-service/ratelimit"
+	"margwa/auth-service/risk"
+	"margwa/auth-service/sms"
+	"margwa/auth-service/totp"
 	"margwa/auth-service/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
 type AuthHandler struct {
-	db     *pgxpool.Pool
-	redis  *redis.Client
-	config *config.Config
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	config   *config.Config
+	sms      sms.SMSSender
+	mail     mail.Sender
+	tokens   *auth.Denylist
+	keys     *keys.KeySet
+	limiter  *ratelimit.Limiter
+	audit    *audit.AuditLogger
+	risk     *risk.Assessor
+	notifier *notify.Client
+
+	totpSealer *totp.Sealer
 }
 
-func NewAuthHandler(db *pgxpool.Pool, redis *redis.Client, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db *pgxpool.Pool, redis *redis.Client, cfg *config.Config, smsSender sms.SMSSender, mailSender mail.Sender, tokens *auth.Denylist, signingKeys *keys.KeySet, totpSealer *totp.Sealer) *AuthHandler {
 	return &AuthHandler{
-		db:     db,
-		redis:  redis,
-		config: cfg,
+		db:         db,
+		redis:      redis,
+		config:     cfg,
+		sms:        smsSender,
+		mail:       mailSender,
+		tokens:     tokens,
+		keys:       signingKeys,
+		totpSealer: totpSealer,
+		limiter: ratelimit.New(redis, ratelimit.CooldownPolicy{
+			Threshold: cfg.OTPFailureThreshold,
+			Window:    cfg.OTPFailureWindow,
+			Base:      cfg.OTPCooldownBase,
+			Max:       cfg.OTPCooldownMax,
+		}),
+		audit: audit.NewAuditLogger(db),
+		risk: risk.NewAssessor(db, risk.Policy{
+			MediumThreshold: cfg.RiskMediumThreshold,
+			HighThreshold:   cfg.RiskHighThreshold,
+			FailureBurst:    cfg.RiskOTPFailureBurst,
+			FailureWindow:   cfg.RiskOTPFailureWindow,
+			MaxTravelSpeed:  float64(cfg.RiskMaxTravelSpeedKmh),
+		}),
+		notifier: notify.NewClient(cfg.NotificationServiceURL),
 	}
 }
 
+// tokenSession is the per-session state embedded in access tokens
+type tokenSession struct {
+	ID            string
+	Persona       string
+	MFAVerifiedAt *time.Time
+}
+
+// generateAccessToken embeds the user's current roles and the session's
+// persona and authentication methods, and signs with the active asymmetric
+// key, or with the shared HS256 secret while no signing keys are configured
+func (h *AuthHandler) generateAccessToken(user *models.User, session tokenSession) (string, error) {
+	if err := h.loadAccess(context.Background(), user); err != nil {
+		return "", fmt.Errorf("load roles: %w", err)
+	}
+
+	claims := utils.NewClaims(user, session.ID, h.config.JWTExpiresIn)
+	claims.Persona = session.Persona
+	claims.AuthMethods = authMethods(session.MFAVerifiedAt)
+	return h.signAccessToken(context.Background(), claims)
+}
+
+func (h *AuthHandler) signAccessToken(ctx context.Context, claims auth.Claims) (string, error) {
+	// The denylist rejects tokens issued in the second of a RevokeUser or
+	// RevokeSessions, so a token issued in that same second, e.g. by logging
+	// in again right after a phone change, is dated into the next one
+	revokedAt, err := h.tokens.RevokedAt(ctx, claims.UserID, claims.SessionID)
+	if err != nil {
+		logging.Warnf(ctx, "Error reading token revocation for %s: %v", claims.UserID, err)
+	} else if !revokedAt.IsZero() && !claims.IssuedAt.After(revokedAt) {
+		claims.IssuedAt = jwt.NewNumericDate(revokedAt.Add(time.Second))
+	}
+
+	if h.keys.Enabled() {
+		return h.keys.Sign(claims)
+	}
+	return utils.SignJWT(claims, h.config.JWTSecret)
+}
+
+// JWKS publishes the public keys other services use to verify access tokens
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
 // Register creates a new user account or upgrades existing user role
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	phone, countryCode, err := utils.NormalizePhoneNumber(req.PhoneCountryCode, req.PhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_PHONE_NUMBER", "Invalid phone number", err.Error()))
 		return
 	}
 
 	// Check if user already exists
 	var existingUser models.User
-	err := h.db.QueryRow(context.Background(),
-		`SELECT id, user_type FROM users WHERE phone_number = $1 AND phone_country_code = $2`,
-		req.PhoneNumber, req.PhoneCountryCode,
+	err = h.db.QueryRow(tracing.Context(c),
+		`SELECT id, user_type FROM users WHERE phone_number = $1`,
+		phone,
 	).Scan(&existingUser.ID, &existingUser.UserType)
 
 	if err == nil {
@@ -51,21 +146,25 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		if existingUser.UserType != "both" && existingUser.UserType != req.UserType {
 			// Existing user registered in one app, now registering in the other
 			// Upgrade to 'both'
-			log.Printf("Upgrading user %s from '%s' to 'both'", existingUser.ID, existingUser.UserType)
-
-			_, err = h.db.Exec(context.Background(),
+			previousType := existingUser.UserType
+			_, err = h.db.Exec(tracing.Context(c),
 				`UPDATE users SET user_type = 'both', updated_at = NOW() WHERE id = $1`,
 				existingUser.ID,
 			)
 
 			if err != nil {
-				log.Printf("Error upgrading user role: %v", err)
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to upgrade user role", nil))
+				logging.Errorf(tracing.Context(c), "Error upgrading user role: %v", err)
+				c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to upgrade user role", nil))
 				return
 			}
 
+			h.recordEvent(c, existingUser.ID.String(), audit.EventRoleUpgrade, nil, map[string]interface{}{
+				"from": previousType,
+				"to":   "both",
+			})
+
 			// Fetch updated user
-			err = h.db.QueryRow(context.Background(),
+			err = h.db.QueryRow(tracing.Context(c),
 				`SELECT id, phone_number, phone_country_code, full_name, email, profile_image_url, user_type, 
 				        is_verified, is_active, language_preference, created_at, updated_at, last_login_at
 				 FROM users WHERE id = $1`,
@@ -75,203 +174,354 @@ func (h *AuthHandler) Register(c *gin.Context) {
 				&existingUser.IsActive, &existingUser.LanguagePreference, &existingUser.CreatedAt,
 				&existingUser.UpdatedAt, &existingUser.LastLoginAt)
 
-			c.JSON(http.StatusOK, utils.SuccessResponse(existingUser, "User role upgraded to 'both'. Please verify with OTP."))
+			c.JSON(http.StatusOK, response.Success(existingUser, "User role upgraded to 'both'. Please verify with OTP."))
 			return
 		}
 
 		// User already has this role or 'both'
-		c.JSON(http.StatusConflict, utils.ErrorResponse("USER_ALREADY_EXISTS", "User with this phone number already exists", nil))
+		c.JSON(http.StatusConflict, response.Error("USER_ALREADY_EXISTS", "User with this phone number already exists", nil))
 		return
 	}
 
 	// Create new user
 	var user models.User
-	err = h.db.QueryRow(context.Background(),
+	err = h.db.QueryRow(tracing.Context(c),
 		`INSERT INTO users (phone_number, phone_country_code, user_type, is_verified, is_active, language_preference)
 		 VALUES ($1, $2, $3, false, true, 'en')
 		 RETURNING id, phone_number, phone_country_code, full_name, email, profile_image_url, user_type, 
 		           is_verified, is_active, language_preference, created_at, updated_at, last_login_at`,
-		req.PhoneNumber, req.PhoneCountryCode, req.UserType,
+		phone, countryCode, req.UserType,
 	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email,
 		&user.ProfileImageURL, &user.UserType, &user.IsVerified, &user.IsActive,
 		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
 
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to create user", nil))
+		logging.Errorf(tracing.Context(c), "Error creating user: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to create user", nil))
 		return
 	}
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse(user, "User registered successfully. Please verify with OTP."))
+	h.recordEvent(c, user.ID.String(), audit.EventRegister, nil, map[string]interface{}{
+		"userType": user.UserType,
+	})
+
+	c.JSON(http.StatusCreated, response.Success(user, "User registered successfully. Please verify with OTP."))
 }
 
-// SendOTP generates and sends an OTP to the user's phone
+// SendOTP generates and sends an OTP to the user's phone, or to their
+// verified email when asked to or when the SMS cannot be delivered
 func (h *AuthHandler) SendOTP(c *gin.Context) {
 	var req models.SendOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	phone, _, err := utils.NormalizePhoneNumber(req.PhoneCountryCode, req.PhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_PHONE_NUMBER", "Invalid phone number", err.Error()))
+		return
+	}
+	if !h.enforceOTPLimits(c, "send", phone, req.DeviceID) {
 		return
 	}
 
 	// Check if user exists
 	var userID uuid.UUID
-	err := h.db.QueryRow(context.Background(),
-		"SELECT id FROM users WHERE phone_number = $1 AND phone_country_code = $2",
-		req.PhoneNumber, req.PhoneCountryCode,
-	).Scan(&userID)
+	var verifiedEmail *string
+	err = h.db.QueryRow(tracing.Context(c),
+		`SELECT id, CASE WHEN email_verified_at IS NOT NULL THEN email END
+		 FROM users WHERE phone_number = $1`,
+		phone,
+	).Scan(&userID, &verifiedEmail)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("USER_NOT_FOUND", "User not found", nil))
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
 		return
 	}
 
-	// Generate OTP
-	otpCode, err := utils.GenerateOTP(h.config.OTPLength)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("INTERNAL_ERROR", "Failed to generate OTP", nil))
+	channel := "sms"
+	if req.Channel != nil {
+		channel = *req.Channel
+	}
+	email := ""
+	if verifiedEmail != nil {
+		email = *verifiedEmail
+	}
+	if channel == "email" && email == "" {
+		c.JSON(http.StatusBadRequest, response.Error("EMAIL_NOT_VERIFIED", "No verified email on this account", nil))
 		return
 	}
 
-	// Store OTP in database
-	expiresAt := time.Now().Add(time.Duration(h.config.OTPExpiryMinutes) * time.Minute)
-	otpID := uuid.New()
-
-	_, err = h.db.Exec(context.Background(),
-		`INSERT INTO otp_verifications (id, user_id, phone_number, otp_code, expires_at, attempts)
-		 VALUES ($1, $2, $3, $4, $5, 0)`,
-		otpID, userID, req.PhoneNumber, otpCode, expiresAt,
-	)
-
+	otp, err := h.issueOTP(c.Request.Context(), userID, phone, "login", channel, email)
+	// Fall back to a verified email when the SMS could not be delivered
+	if errors.Is(err, errOTPDelivery) && channel == "sms" && email != "" {
+		channel = "email"
+		otp, err = h.issueOTP(c.Request.Context(), userID, phone, "login", channel, email)
+	}
+	if errors.Is(err, errOTPDelivery) {
+		c.JSON(http.StatusBadGateway, response.Error("OTP_DELIVERY_FAILED", "Failed to deliver OTP, please try again", nil))
+		return
+	}
 	if err != nil {
-		log.Printf("Error storing OTP: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to store OTP", nil))
+		logging.Errorf(tracing.Context(c), "Error issuing OTP: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to issue OTP", nil))
 		return
 	}
 
-	// TODO: Send OTP via Twilio SMS
-	// For development, log the OTP
-	log.Printf("OTP for %s: %s (Expires at: %v)", req.PhoneNumber, otpCode, expiresAt)
+	h.recordEvent(c, userID.String(), audit.EventOTPSent, req.DeviceID, map[string]interface{}{
+		"channel": channel,
+	})
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"otpId":     otpID,
-		"expiresAt": expiresAt,
-		"message":   fmt.Sprintf("OTP sent to %s", req.PhoneNumber),
-		// In development, return OTP in response
-		"otp": otpCode,
-	}, "OTP sent successfully"))
+	destination := phone
+	if channel == "email" {
+		destination = utils.MaskEmail(email)
+	}
+	data := gin.H{
+		"otpId":     otp.ID,
+		"expiresAt": otp.ExpiresAt,
+		"channel":   channel,
+		"message":   fmt.Sprintf("OTP sent to %s", destination),
+	}
+	// Outside production, return OTP in response
+	if h.config.Environment != "production" {
+		data["otp"] = otp.Code
+	}
+
+	c.JSON(http.StatusOK, response.Success(data, "OTP sent successfully"))
 }
 
 // VerifyOTP verifies the OTP and logs in the user
 func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	var req models.VerifyOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	phone, _, err := utils.NormalizePhoneNumber(req.PhoneCountryCode, req.PhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_PHONE_NUMBER", "Invalid phone number", err.Error()))
+		return
+	}
+	if !h.enforceOTPLimits(c, "verify", phone, req.DeviceID) {
 		return
 	}
-	log.Printf(req.PhoneNumber, req.PhoneCountryCode)
+
 	// Get user
 	var user models.User
-	err := h.db.QueryRow(context.Background(),
-		`SELECT id, phone_number, phone_country_code, full_name, email, profile_image_url, user_type,
+	err = h.db.QueryRow(tracing.Context(c),
+		`SELECT id, phone_number, phone_country_code, full_name, email, email_verified_at, profile_image_url, user_type,
 		  is_verified, is_active, language_preference, created_at, updated_at, last_login_at
-		 FROM users WHERE phone_number = $1 AND phone_country_code = $2`,
-		req.PhoneNumber, req.PhoneCountryCode,
-	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email,
+		 FROM users WHERE phone_number = $1`,
+		phone,
+	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email, &user.EmailVerifiedAt,
 		&user.ProfileImageURL, &user.UserType, &user.IsVerified, &user.IsActive,
 		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("USER_NOT_FOUND", "User not found", nil))
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+		return
+	}
+
+	persona, ok := resolvePersona(c, user.UserType, req.Persona)
+	if !ok {
+		return
+	}
+
+	// Verify the OTP and log in within one transaction. The OTP row is locked
+	// so parallel requests for the same code serialize: the second one sees
+	// the updated attempts or verified_at and cannot also succeed.
+	ctx := tracing.Context(c)
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	// Verify OTP
 	var otp models.OTPVerification
-	err = h.db.QueryRow(context.Background(),
+	err = tx.QueryRow(ctx,
 		`SELECT id, otp_code, expires_at, verified_at, attempts
 		 FROM otp_verifications 
-		 WHERE user_id = $1 AND phone_number = $2 AND verified_at IS NULL
-		 ORDER BY created_at DESC LIMIT 1`,
-		user.ID, req.PhoneNumber,
+		 WHERE user_id = $1 AND phone_number = $2 AND purpose = 'login'
+		   AND verified_at IS NULL AND delivery_status <> 'failed'
+		 ORDER BY created_at DESC LIMIT 1
+		 FOR UPDATE`,
+		user.ID, phone,
 	).Scan(&otp.ID, &otp.OTPCode, &otp.ExpiresAt, &otp.VerifiedAt, &otp.Attempts)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, response.Error("OTP_NOT_FOUND", "No valid OTP found", nil))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("OTP_NOT_FOUND", "No valid OTP found", nil))
+		logging.Errorf(ctx, "Error loading OTP: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
 		return
 	}
 
-	// Check if OTP expired
-	if time.Now().After(otp.ExpiresAt) {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("OTP_EXPIRED", "OTP has expired", nil))
+	// Check if OTP expired (plaintext rows from before hashing count as expired)
+	if time.Now().After(otp.ExpiresAt) || !utils.IsHashedOTP(otp.OTPCode) {
+		otpVerificationFailures.WithLabelValues("login", "expired").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("OTP_EXPIRED", "OTP has expired", nil))
 		return
 	}
 
 	// Check attempts
 	if otp.Attempts >= 3 {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("TOO_MANY_ATTEMPTS", "Too many failed attempts", nil))
+		otpVerificationFailures.WithLabelValues("login", "too_many_attempts").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("TOO_MANY_ATTEMPTS", "Too many failed attempts", nil))
 		return
 	}
 
 	// Verify OTP code
-	if otp.OTPCode != req.OTPCode {
-		// Increment attempts
-		h.db.Exec(context.Background(),
+	if !utils.VerifyOTPHash(h.config.OTPSecret, otp.ID.String(), req.OTPCode, otp.OTPCode) {
+		// Increment attempts and commit so the failure sticks
+		_, err = tx.Exec(ctx,
 			"UPDATE otp_verifications SET attempts = attempts + 1 WHERE id = $1",
 			otp.ID,
 		)
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("INVALID_OTP", "Invalid OTP code", nil))
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			logging.Errorf(ctx, "Error recording OTP attempt: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+			return
+		}
+
+		if err := h.limiter.RecordFailure(ctx, otpSubjects(c, phone)...); err != nil {
+			logging.Errorf(ctx, "Error recording OTP failure: %v", err)
+		}
+		h.recordEvent(c, user.ID.String(), audit.EventOTPFailed, req.DeviceID, map[string]interface{}{
+			"attempt": otp.Attempts + 1,
+		})
+		otpVerificationFailures.WithLabelValues("login", "invalid").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_OTP", "Invalid OTP code", nil))
 		return
 	}
 
+	// Score the login against previous devices, networks and locations. A
+	// high-risk login leaves the SMS code unused until the second factor passes.
+	assessment := h.assessLogin(c, user.ID.String(), &req)
+	var stepUpMethod *string
+	if h.requiresStepUp(assessment) {
+		method, ok := h.verifyStepUp(c, tx, &user, &req, assessment)
+		if !ok {
+			return
+		}
+		if method != "" {
+			stepUpMethod = &method
+		}
+	}
+
 	// Mark OTP as verified
 	now := time.Now()
-	h.db.Exec(context.Background(),
+	if _, err := tx.Exec(ctx,
 		"UPDATE otp_verifications SET verified_at = $1 WHERE id = $2",
 		now, otp.ID,
-	)
+	); err != nil {
+		logging.Errorf(ctx, "Error marking OTP verified: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+		return
+	}
 
 	// Mark user as verified
-	h.db.Exec(context.Background(),
+	if _, err := tx.Exec(ctx,
 		"UPDATE users SET is_verified = true, last_login_at = $1 WHERE id = $2",
 		now, user.ID,
-	)
+	); err != nil {
+		logging.Errorf(ctx, "Error updating user login: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+		return
+	}
 	user.IsVerified = true
 	user.LastLoginAt = &now
 
 	// Generate tokens
-	accessTokenDuration := utils.ParseDuration(h.config.JWTExpiresIn)
-	refreshTokenDuration := utils.ParseDuration(h.config.JWTRefreshExpires)
+	refreshTokenDuration := h.config.JWTRefreshExpires
+	sessionID := uuid.New()
 
-	accessToken, err := utils.GenerateJWT(&user, h.config.JWTSecret, accessTokenDuration)
+	// Passing TOTP or a recovery code as the step-up also satisfies MFA
+	var mfaVerifiedAt *time.Time
+	if stepUpMethod != nil && *stepUpMethod != "email" {
+		mfaVerifiedAt = &now
+	}
+
+	accessToken, err := h.generateAccessToken(&user, tokenSession{
+		ID:            sessionID.String(),
+		Persona:       persona,
+		MFAVerifiedAt: mfaVerifiedAt,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("INTERNAL_ERROR", "Failed to generate access token", nil))
+		logging.Errorf(ctx, "Error generating access token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
 		return
 	}
 
-	refreshToken, err := utils.GenerateJWT(&user, h.config.JWTRefreshSecret, refreshTokenDuration)
+	refreshToken, err := utils.GenerateJWT(&user, sessionID.String(), h.config.JWTRefreshSecret, refreshTokenDuration)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("INTERNAL_ERROR", "Failed to generate refresh token", nil))
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate refresh token", nil))
 		return
 	}
 
 	// Store session
-	sessionID := uuid.New()
 	sessionExpiresAt := time.Now().Add(refreshTokenDuration)
 
-	_, err = h.db.Exec(context.Background(),
-		`INSERT INTO sessions (id, user_id, refresh_token, device_id, device_type, fcm_token, expires_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		sessionID, user.ID, refreshToken, req.DeviceID, req.DeviceType, req.FCMToken, sessionExpiresAt,
+	var riskScore *int
+	var riskLevel *string
+	var riskReasons []string
+	if assessment != nil {
+		riskScore, riskLevel, riskReasons = &assessment.Score, &assessment.Level, assessment.Reasons
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO sessions (id, user_id, refresh_token, device_id, device_type, fcm_token, ip_address, expires_at, last_used_at,
+		                       persona, risk_score, risk_level, risk_reasons, step_up_method, mfa_verified_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), $9, $10, $11, $12, $13, $14)`,
+		sessionID, user.ID, refreshToken, req.DeviceID, req.DeviceType, req.FCMToken, c.ClientIP(), sessionExpiresAt,
+		persona, riskScore, riskLevel, riskReasons, stepUpMethod, mfaVerifiedAt,
 	)
 
 	if err != nil {
-		log.Printf("Error storing session: %v", err)
+		logging.Errorf(ctx, "Error storing session: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to create session", nil))
+		return
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		logging.Errorf(ctx, "Error committing login: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+		return
+	}
+
+	if err := h.limiter.ResetFailures(ctx, "phone:"+phone); err != nil {
+		logging.Errorf(ctx, "Error resetting OTP failures: %v", err)
+	}
+
+	loginMetadata := map[string]interface{}{
+		"sessionId":  sessionID,
+		"deviceType": req.DeviceType,
+		"persona":    persona,
+	}
+	if assessment != nil {
+		loginMetadata["riskScore"] = assessment.Score
+		loginMetadata["riskLevel"] = assessment.Level
+		loginMetadata["riskReasons"] = assessment.Reasons
+	}
+	if stepUpMethod != nil {
+		loginMetadata["stepUpMethod"] = *stepUpMethod
+	}
+	h.recordEvent(c, user.ID.String(), audit.EventLogin, req.DeviceID, loginMetadata)
+	h.notifyRiskyLogin(ctx, &user, sessionID.String(), assessment, req.DeviceType, c.ClientIP())
+
 	tokens := models.TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresIn:    h.config.JWTExpiresIn,
+		ExpiresIn:    env.FormatDuration(h.config.JWTExpiresIn),
+		Persona:      persona,
 	}
 
 	result := models.UserWithTokens{
@@ -279,70 +529,190 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 		Tokens: &tokens,
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(result, "Login successful"))
+	c.JSON(http.StatusOK, response.Success(result, "Login successful"))
 }
 
-// RefreshToken generates a new access token using refresh token
+// RefreshToken rotates the refresh token and issues a new access token.
+// Each session row is a token family: its ID travels in the refresh token's
+// sid claim and stays fixed while the token itself is replaced on every
+// refresh. Presenting a token that has already been rotated revokes the
+// whole family.
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req models.RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 		return
 	}
 
 	// Validate refresh token
 	claims, err := utils.ValidateJWT(req.RefreshToken, h.config.JWTRefreshSecret)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("INVALID_TOKEN", "Invalid refresh token", nil))
+		c.JSON(http.StatusUnauthorized, response.Error("INVALID_TOKEN", "Invalid refresh token", nil))
+		return
+	}
+
+	// Look up the session holding this exact token
+	var session models.Session
+	err = h.db.QueryRow(tracing.Context(c),
+		`SELECT id, user_id, device_id, expires_at, persona, mfa_verified_at FROM sessions WHERE refresh_token = $1`,
+		req.RefreshToken,
+	).Scan(&session.ID, &session.UserID, &session.DeviceID, &session.ExpiresAt, &session.Persona, &session.MFAVerifiedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.rejectStaleRefreshToken(c, claims)
+		return
+	}
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error loading session: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to load session", nil))
+		return
+	}
+
+	if session.UserID.String() != claims.UserID {
+		c.JSON(http.StatusUnauthorized, response.Error("INVALID_TOKEN", "Invalid refresh token", nil))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		h.db.Exec(tracing.Context(c), "DELETE FROM sessions WHERE id = $1", session.ID)
+		c.JSON(http.StatusUnauthorized, response.Error("SESSION_EXPIRED", "Session has expired, please log in again", nil))
 		return
 	}
 
 	// Get user
-	userID, _ := uuid.Parse(claims.UserID)
 	var user models.User
-	err = h.db.QueryRow(context.Background(),
+	err = h.db.QueryRow(tracing.Context(c),
 		`SELECT id, phone_number, phone_country_code, full_name, email, profile_image_url, user_type,
 		  is_verified, is_active, language_preference, created_at, updated_at, last_login_at
 		 FROM users WHERE id = $1`,
-		userID,
+		session.UserID,
 	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email,
 		&user.ProfileImageURL, &user.UserType, &user.IsVerified, &user.IsActive,
 		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("USER_NOT_FOUND", "User not found", nil))
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
 		return
 	}
 
-	// Generate new access token
-	accessTokenDuration := utils.ParseDuration(h.config.JWTExpiresIn)
-	accessToken, err := utils.GenerateJWT(&user, h.config.JWTSecret, accessTokenDuration)
+	// Generate new token pair
+	refreshTokenDuration := h.config.JWTRefreshExpires
+
+	accessToken, err := h.generateAccessToken(&user, tokenSession{
+		ID:            session.ID.String(),
+		Persona:       session.Persona,
+		MFAVerifiedAt: session.MFAVerifiedAt,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("INTERNAL_ERROR", "Failed to generate access token", nil))
+		logging.Errorf(tracing.Context(c), "Error generating access token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"accessToken": accessToken,
-		"expiresIn":   h.config.JWTExpiresIn,
+	refreshToken, err := utils.GenerateJWT(&user, session.ID.String(), h.config.JWTRefreshSecret, refreshTokenDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate refresh token", nil))
+		return
+	}
+
+	// Swap the token only if no concurrent refresh got there first
+	tag, err := h.db.Exec(tracing.Context(c),
+		`UPDATE sessions
+		 SET refresh_token = $1, expires_at = $2, last_used_at = NOW(), ip_address = $3
+		 WHERE id = $4 AND refresh_token = $5`,
+		refreshToken, time.Now().Add(refreshTokenDuration), c.ClientIP(), session.ID, req.RefreshToken,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error rotating session %s: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to rotate session", nil))
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		h.rejectStaleRefreshToken(c, claims)
+		return
+	}
+
+	h.recordEvent(c, user.ID.String(), audit.EventTokenRefresh, session.DeviceID, map[string]interface{}{
+		"sessionId": session.ID,
+	})
+
+	c.JSON(http.StatusOK, response.Success(models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    env.FormatDuration(h.config.JWTExpiresIn),
+		Persona:      session.Persona,
 	}, "Token refreshed successfully"))
 }
 
-// Logout invalidates the user's session
+// rejectStaleRefreshToken handles a correctly signed refresh token that no
+// session holds. If its family still exists the token was already rotated,
+// so it is being replayed and the family is revoked.
+func (h *AuthHandler) rejectStaleRefreshToken(c *gin.Context, claims *auth.Claims) {
+	if claims.SessionID != "" {
+		tag, err := h.db.Exec(tracing.Context(c),
+			"DELETE FROM sessions WHERE id = $1 AND user_id = $2",
+			claims.SessionID, claims.UserID,
+		)
+		if err != nil {
+			logging.Errorf(tracing.Context(c), "Error revoking session %s: %v", claims.SessionID, err)
+		} else if tag.RowsAffected() > 0 {
+			h.recordEvent(c, claims.UserID, audit.EventTokenReuse, nil, map[string]interface{}{
+				"sessionId": claims.SessionID,
+			})
+			if err := h.tokens.RevokeSessions(tracing.Context(c), claims.SessionID); err != nil {
+				logging.Errorf(tracing.Context(c), "Error denylisting session %s: %v", claims.SessionID, err)
+			}
+			c.JSON(http.StatusUnauthorized, response.Error("TOKEN_REUSED", "Refresh token was already used; session revoked", nil))
+			return
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, response.Error("SESSION_REVOKED", "Session is no longer active, please log in again", nil))
+}
+
+// Logout revokes the session the access token belongs to
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID := c.GetString("userId")
-
-	// Delete all sessions for the user
-	_, err := h.db.Exec(context.Background(),
-		"DELETE FROM sessions WHERE user_id = $1",
-		userID,
-	)
+	sessionID := c.GetString("sessionId")
+
+	var err error
+	switch {
+	case c.GetString("actorId") != "":
+		// Impersonation tokens have no session; ending one must not sign
+		// the impersonated user out
+	case sessionID != "":
+		_, err = h.db.Exec(tracing.Context(c),
+			"DELETE FROM sessions WHERE id = $1 AND user_id = $2",
+			sessionID, userID,
+		)
+		if err == nil {
+			err = h.tokens.RevokeSessions(tracing.Context(c), sessionID)
+		}
+	default:
+		// Tokens issued before session IDs were embedded cannot identify
+		// their session, so fall back to revoking all of them
+		_, err = h.db.Exec(tracing.Context(c),
+			"DELETE FROM sessions WHERE user_id = $1",
+			userID,
+		)
+		if err == nil {
+			err = h.tokens.RevokeUser(tracing.Context(c), userID)
+		}
+	}
 
 	if err != nil {
-		log.Printf("Error deleting sessions: %v", err)
+		logging.Errorf(tracing.Context(c), "Error revoking sessions: %v", err)
+	}
+
+	if err := h.tokens.RevokeToken(tracing.Context(c), c.GetString("tokenId"), c.GetTime("tokenExpiresAt")); err != nil {
+		logging.Errorf(tracing.Context(c), "Error denylisting access token: %v", err)
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(nil, "Logged out successfully"))
+	h.recordEvent(c, userID, audit.EventLogout, nil, map[string]interface{}{
+		"sessionId": sessionID,
+	})
+
+	c.JSON(http.StatusOK, response.Success(nil, "Logged out successfully"))
 }
 
 // GetProfile returns the user's profile
@@ -350,21 +720,21 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID := c.GetString("userId")
 
 	var user models.User
-	err := h.db.QueryRow(context.Background(),
-		`SELECT id, phone_number, phone_country_code, full_name, email, profile_image_url, user_type,
-		  is_verified, is_active, language_preference, created_at, updated_at, last_login_at
+	err := h.db.QueryRow(tracing.Context(c),
+		`SELECT id, phone_number, phone_country_code, full_name, email, email_verified_at, profile_image_url, user_type,
+		  is_verified, is_active, language_preference, created_at, updated_at, last_login_at, deletion_scheduled_at
 		 FROM users WHERE id = $1`,
 		userID,
-	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email,
+	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email, &user.EmailVerifiedAt,
 		&user.ProfileImageURL, &user.UserType, &user.IsVerified, &user.IsActive,
-		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
+		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.DeletionScheduledAt)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("USER_NOT_FOUND", "User not found", nil))
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(user, "Profile retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(user, "Profile retrieved successfully"))
 }
 
 // UpdateProfile updates the user's profile
@@ -373,15 +743,16 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 
 	var req models.UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 		return
 	}
 
 	// Update user
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE users SET 
 		  full_name = COALESCE($1, full_name),
 		  email = COALESCE($2, email),
+		  email_verified_at = CASE WHEN $2 IS NOT NULL AND $2 IS DISTINCT FROM email THEN NULL ELSE email_verified_at END,
 		  profile_image_url = COALESCE($3, profile_image_url),
 		  dob = COALESCE($4, dob),
 		  gender = COALESCE($5, gender),
@@ -393,21 +764,42 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error updating profile: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update profile", nil))
+		logging.Errorf(tracing.Context(c), "Error updating profile: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update profile", nil))
 		return
 	}
 
+	var changed []string
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"fullName", req.FullName != nil},
+		{"email", req.Email != nil},
+		{"profileImageUrl", req.ProfileImageURL != nil},
+		{"dob", req.DateOfBirth != nil},
+		{"gender", req.Gender != nil},
+		{"isProfileComplete", req.IsProfileComplete != nil},
+		{"languagePreference", req.LanguagePreference != nil},
+	} {
+		if field.set {
+			changed = append(changed, field.name)
+		}
+	}
+	h.recordEvent(c, userID, audit.EventProfileChange, nil, map[string]interface{}{
+		"fields": changed,
+	})
+
 	// Get updated user
 	var user models.User
-	h.db.QueryRow(context.Background(),
-		`SELECT id, phone_number, phone_country_code, full_name, email, profile_image_url, dob, gender, is_profile_complete, user_type,
+	h.db.QueryRow(tracing.Context(c),
+		`SELECT id, phone_number, phone_country_code, full_name, email, email_verified_at, profile_image_url, dob, gender, is_profile_complete, user_type,
 		  is_verified, is_active, language_preference, created_at, updated_at, last_login_at
 		 FROM users WHERE id = $1`,
 		userID,
-	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email,
+	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email, &user.EmailVerifiedAt,
 		&user.ProfileImageURL, &user.DateOfBirth, &user.Gender, &user.IsProfileComplete, &user.UserType, &user.IsVerified, &user.IsActive,
 		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(user, "Profile updated successfully"))
+	c.JSON(http.StatusOK, response.Success(user, "Profile updated successfully"))
 }
@@ -0,0 +1,62 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"margwa/auth-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	s := newTestServer(t, "development")
+	tokens := s.login(t)
+
+	if status, env := s.do(t, http.MethodGet, "/auth/profile", tokens.AccessToken, nil); status != http.StatusOK {
+		t.Fatalf("profile before logout: status %d, body %+v", status, env)
+	}
+	if status, env := s.do(t, http.MethodPost, "/auth/logout", tokens.AccessToken, nil); status != http.StatusOK {
+		t.Fatalf("logout: status %d, body %+v", status, env)
+	}
+
+	// The token has not expired, so only the denylist can reject it
+	status, _ := s.do(t, http.MethodGet, "/auth/profile", tokens.AccessToken, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("profile after logout: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	s := newTestServer(t, "development")
+	first := s.login(t)
+
+	status, env := s.do(t, http.MethodPost, "/auth/refresh-token", "", gin.H{"refreshToken": first.RefreshToken})
+	if status != http.StatusOK {
+		t.Fatalf("refresh: status %d, body %+v", status, env)
+	}
+	var second models.TokenPair
+	if err := json.Unmarshal(env.Data, &second); err != nil {
+		t.Fatal(err)
+	}
+	if second.AccessToken == "" || second.RefreshToken == "" {
+		t.Fatalf("refresh: no tokens in %s", env.Data)
+	}
+
+	// Replaying the rotated token looks like theft: the session goes
+	status, env = s.do(t, http.MethodPost, "/auth/refresh-token", "", gin.H{"refreshToken": first.RefreshToken})
+	if status != http.StatusUnauthorized || env.Error == nil || env.Error.Code != "TOKEN_REUSED" {
+		t.Fatalf("replayed refresh: status %d, body %+v, want 401 TOKEN_REUSED", status, env)
+	}
+
+	// ...and with it every token the session issued, including the newest
+	if status, env := s.do(t, http.MethodPost, "/auth/refresh-token", "", gin.H{"refreshToken": second.RefreshToken}); status != http.StatusUnauthorized {
+		t.Errorf("current refresh token after reuse: status %d, body %+v, want 401", status, env)
+	}
+	for name, token := range map[string]string{"first": first.AccessToken, "second": second.AccessToken} {
+		if status, _ := s.do(t, http.MethodGet, "/auth/profile", token, nil); status != http.StatusUnauthorized {
+			t.Errorf("%s access token after reuse: status %d, want 401", name, status)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+	"margwa/auth-service/ratelimit"
+	"margwa/auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// StartEmailVerification mails a signed magic link to the user's email
+func (h *AuthHandler) StartEmailVerification(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	var email *string
+	var verifiedAt *time.Time
+	err := h.db.QueryRow(tracing.Context(c),
+		"SELECT email, email_verified_at FROM users WHERE id = $1",
+		userID,
+	).Scan(&email, &verifiedAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+		return
+	}
+
+	if email == nil || *email == "" {
+		c.JSON(http.StatusBadRequest, response.Error("EMAIL_NOT_SET", "Add an email to your profile first", nil))
+		return
+	}
+	if verifiedAt != nil {
+		c.JSON(http.StatusConflict, response.Error("EMAIL_ALREADY_VERIFIED", "Email is already verified", nil))
+		return
+	}
+
+	allowed, wait, err := h.limiter.Allow(tracing.Context(c), ratelimit.Rule{
+		Key:    "email-verify:user:" + userID,
+		Limit:  h.config.OTPSendLimitPerPhone,
+		Window: h.config.OTPSendWindow,
+	})
+	if err != nil {
+		logging.Warnf(tracing.Context(c), "Rate limiter unavailable: %v", err)
+	} else if !allowed {
+		rejectRateLimited(c, wait)
+		return
+	}
+
+	token, err := utils.GenerateEmailToken(userID, *email, h.config.EmailTokenSecret, h.config.EmailVerificationTTL)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error signing email token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to create verification link", nil))
+		return
+	}
+
+	link := h.config.EmailVerifyURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Confirm your email address for Margwa by opening this link:\n\n%s\n\nThe link expires in %s. If you did not ask for this, you can ignore this email.",
+		link, h.config.EmailVerificationTTL)
+	if err := h.mail.Send(c.Request.Context(), *email, "Verify your email for Margwa", body); err != nil {
+		logging.Errorf(tracing.Context(c), "Error sending verification email to user %s: %v", userID, err)
+		c.JSON(http.StatusBadGateway, response.Error("EMAIL_DELIVERY_FAILED", "Failed to send verification email, please try again", nil))
+		return
+	}
+
+	data := gin.H{
+		"message":   fmt.Sprintf("Verification link sent to %s", utils.MaskEmail(*email)),
+		"expiresAt": time.Now().Add(h.config.EmailVerificationTTL),
+	}
+	// Outside production, return the token in response
+	if h.config.Environment != "production" {
+		data["token"] = token
+	}
+
+	c.JSON(http.StatusOK, response.Success(data, "Verification email sent"))
+}
+
+// ConfirmEmailVerification marks the email verified from a magic-link token.
+// It needs no access token so the link works on any device.
+func (h *AuthHandler) ConfirmEmailVerification(c *gin.Context) {
+	var req models.ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	claims, err := utils.ValidateEmailToken(req.Token, h.config.EmailTokenSecret)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_TOKEN", "Verification link is invalid or has expired", nil))
+		return
+	}
+
+	// The email must not have changed since the link was sent
+	var verifiedAt time.Time
+	err = h.db.QueryRow(tracing.Context(c),
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		 WHERE id = $1 AND email = $2
+		 RETURNING email_verified_at`,
+		claims.UserID, claims.Email,
+	).Scan(&verifiedAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_TOKEN", "Verification link is invalid or has expired", nil))
+		return
+	}
+
+	h.recordEvent(c, claims.UserID, audit.EventEmailVerified, nil, nil)
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"email":           claims.Email,
+		"emailVerifiedAt": verifiedAt,
+	}, "Email verified successfully"))
+}
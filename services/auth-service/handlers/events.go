@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// recordEvent writes an auth event with the request's IP and user agent.
+// deviceID falls back to the X-Device-ID header when the body has none, and
+// events caused through an impersonation token name the staff member.
+func (h *AuthHandler) recordEvent(c *gin.Context, userID, eventType string, deviceID *string, metadata map[string]interface{}) {
+	device := c.GetHeader("X-Device-ID")
+	if deviceID != nil && *deviceID != "" {
+		device = *deviceID
+	}
+	if actorID := c.GetString("actorId"); actorID != "" {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadata["actorId"] = actorID
+	}
+
+	h.audit.Log(tracing.Context(c), audit.Event{
+		UserID:    userID,
+		Type:      eventType,
+		IPAddress: c.ClientIP(),
+		DeviceID:  device,
+		UserAgent: c.Request.UserAgent(),
+		Metadata:  metadata,
+	})
+}
+
+// SecurityEvents lists recent activity on the user's account, newest first
+func (h *AuthHandler) SecurityEvents(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	rows, err := h.db.Query(tracing.Context(c),
+		`SELECT id, event_type, ip_address, device_id, user_agent, metadata, created_at
+		 FROM auth_events
+		 WHERE user_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error listing security events: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to load security events", nil))
+		return
+	}
+	defer rows.Close()
+
+	events := []models.SecurityEvent{}
+	for rows.Next() {
+		var e models.SecurityEvent
+		if err := rows.Scan(&e.ID, &e.EventType, &e.IPAddress, &e.DeviceID, &e.UserAgent, &e.Metadata, &e.CreatedAt); err != nil {
+			logging.Errorf(tracing.Context(c), "Error scanning security event: %v", err)
+			continue
+		}
+		events = append(events, e)
+	}
+
+	c.JSON(http.StatusOK, response.Success(events, "Security events retrieved successfully"))
+}
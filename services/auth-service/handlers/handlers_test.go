@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"margwa/auth-service/config"
+	"margwa/auth-service/handlers"
+	"margwa/auth-service/keys"
+	"margwa/auth-service/mail"
+	"margwa/auth-service/models"
+	"margwa/auth-service/sms"
+	"margwa/auth-service/sms/smstest"
+	"margwa/auth-service/totp"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/migrate"
+	"github.com/margwa/shared/go/postgres"
+	"github.com/redis/go-redis/v9"
+)
+
+// testServer is the auth router wired as in main.go, against the database
+// in TEST_DATABASE_URL, an in-memory Redis and the mock Twilio API
+type testServer struct {
+	router *gin.Engine
+	db     *pgxpool.Pool
+	sms    *smstest.Server
+}
+
+// envelope is the shared response body
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+var otpPattern = regexp.MustCompile(`\b(\d{6})\b`)
+
+// newTestServer skips the test unless TEST_DATABASE_URL points at a
+// database whose base tables drizzle has already created
+func newTestServer(t *testing.T, environment string) *testServer {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	db, err := postgres.Connect(ctx, postgres.Config{URL: databaseURL, MaxConns: 20})
+	if err != nil {
+		t.Fatalf("connecting to database: %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err := migrate.Run(ctx, db); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	smsServer := smstest.NewServer()
+	t.Cleanup(smsServer.Close)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	cfg.Environment = environment
+	cfg.SMSProvider = "twilio"
+	cfg.TwilioAPIURL = smsServer.URL
+	cfg.TwilioSID = "ACtest"
+	cfg.TwilioAuthToken = "secret"
+	cfg.TwilioPhoneNumber = "+15550001111"
+	cfg.JWTAcceptHS256 = true
+	cfg.RiskStepUpEnabled = false
+
+	smsSender, err := sms.NewSender(cfg)
+	if err != nil {
+		t.Fatalf("configuring SMS: %v", err)
+	}
+	signingKeys, err := keys.Load("", "")
+	if err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+	sealer, err := totp.NewSealer(cfg.MFAEncryptionKey)
+	if err != nil {
+		t.Fatalf("creating sealer: %v", err)
+	}
+	denylist := auth.NewDenylist(redisClient, "closed", max(cfg.JWTExpiresIn, cfg.ImpersonationTTL))
+	mailSender := mail.NewFileSender(filepath.Join(t.TempDir(), "mail.log"))
+
+	h := handlers.NewAuthHandler(db, redisClient, cfg, smsSender, mailSender, denylist, signingKeys, sealer)
+	requireAuth := auth.Middleware(signingKeys.Keyfunc(cfg.JWTSecret), denylist)
+
+	router := gin.New()
+	routes := router.Group("/auth")
+	routes.POST("/register", h.Register)
+	routes.POST("/send-otp", h.SendOTP)
+	routes.POST("/verify-otp", h.VerifyOTP)
+	routes.POST("/refresh-token", h.RefreshToken)
+	routes.POST("/logout", requireAuth, h.Logout)
+	routes.GET("/profile", requireAuth, h.GetProfile)
+
+	return &testServer{router: router, db: db, sms: smsServer}
+}
+
+// do sends a JSON request and decodes the response envelope. It reports
+// failures with t.Errorf so it can be called from goroutines.
+func (s *testServer) do(t *testing.T, method, path, token string, body interface{}) (int, envelope) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Errorf("%s %s: encoding body: %v", method, path, err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	var env envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Errorf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+	}
+	return rec.Code, env
+}
+
+// registerUser creates a client with a fresh phone number and removes it
+// when the test ends
+func (s *testServer) registerUser(t *testing.T) string {
+	t.Helper()
+	number := fmt.Sprintf("9%09d", rand.Int63n(1e9))
+	status, env := s.do(t, http.MethodPost, "/auth/register", "", gin.H{
+		"phoneNumber": number, "phoneCountryCode": "+91", "userType": "client",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("register: status %d, body %+v", status, env)
+	}
+	t.Cleanup(func() {
+		s.db.Exec(context.Background(), "DELETE FROM users WHERE phone_number = $1", "+91"+number)
+	})
+	return number
+}
+
+// sendOTP requests a login code and returns the one delivered by SMS
+func (s *testServer) sendOTP(t *testing.T, number string) string {
+	t.Helper()
+	status, env := s.do(t, http.MethodPost, "/auth/send-otp", "", gin.H{
+		"phoneNumber": number, "phoneCountryCode": "+91",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("send-otp: status %d, body %+v", status, env)
+	}
+	msg, ok := s.sms.LastMessageTo("+91" + number)
+	if !ok {
+		t.Fatal("send-otp: no SMS delivered")
+	}
+	code := otpPattern.FindStringSubmatch(msg.Body)
+	if code == nil {
+		t.Fatalf("send-otp: no code in %q", msg.Body)
+	}
+	return code[1]
+}
+
+// login registers a user and signs them in with an SMS code
+func (s *testServer) login(t *testing.T) models.TokenPair {
+	t.Helper()
+	number := s.registerUser(t)
+	code := s.sendOTP(t, number)
+	status, env := s.do(t, http.MethodPost, "/auth/verify-otp", "", gin.H{
+		"phoneNumber": number, "phoneCountryCode": "+91", "otpCode": code,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("verify-otp: status %d, body %+v", status, env)
+	}
+	var data models.UserWithTokens
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return *data.Tokens
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"margwa/auth-service/models"
+	"margwa/auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// Impersonate mints a short-lived access token that acts as another user so
+// staff can see what they see. The token carries an act claim naming the
+// staff member, has no session and no refresh token, and is blocked from
+// payment, withdrawal and account-security endpoints.
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	actorID := c.GetString("userId")
+	targetID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid user ID", nil))
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	if targetID.String() == actorID {
+		c.JSON(http.StatusBadRequest, response.Error("CANNOT_IMPERSONATE_SELF", "You cannot impersonate yourself", nil))
+		return
+	}
+
+	var user models.User
+	err = h.db.QueryRow(tracing.Context(c),
+		`SELECT id, phone_number, phone_country_code, full_name, email, profile_image_url, user_type,
+		  is_verified, is_active, language_preference, created_at, updated_at, last_login_at
+		 FROM users WHERE id = $1 AND is_active = true AND deleted_at IS NULL`,
+		targetID,
+	).Scan(&user.ID, &user.PhoneNumber, &user.PhoneCountryCode, &user.FullName, &user.Email,
+		&user.ProfileImageURL, &user.UserType, &user.IsVerified, &user.IsActive,
+		&user.LanguagePreference, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+		return
+	}
+
+	// Acting as another staff member would hand over their permissions
+	if err := h.loadAccess(tracing.Context(c), &user); err != nil {
+		logging.Errorf(tracing.Context(c), "Error loading roles: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to impersonate user", nil))
+		return
+	}
+	if len(user.Roles) > 0 {
+		c.JSON(http.StatusForbidden, response.Error("CANNOT_IMPERSONATE_STAFF", "Users with roles cannot be impersonated", nil))
+		return
+	}
+
+	persona, ok := resolvePersona(c, user.UserType, req.Persona)
+	if !ok {
+		return
+	}
+
+	claims := utils.NewClaims(&user, "", h.config.ImpersonationTTL)
+	claims.Actor = &auth.Actor{UserID: actorID}
+	claims.Persona = persona
+
+	accessToken, err := h.signAccessToken(tracing.Context(c), claims)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error generating impersonation token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
+		return
+	}
+
+	// No audit record, no token
+	_, err = h.db.Exec(tracing.Context(c),
+		`INSERT INTO impersonation_audit
+		  (actor_id, target_user_id, token_id, event, reason, service, method, path, status_code, ip_address)
+		 VALUES ($1, $2, $3, 'issued', $4, 'auth-service', $5, $6, $7, $8)`,
+		actorID, user.ID, claims.ID, req.Reason, c.Request.Method, c.Request.URL.Path, http.StatusOK, c.ClientIP(),
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error writing impersonation audit: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to impersonate user", nil))
+		return
+	}
+
+	logging.Infof(tracing.Context(c), "User %s started impersonating %s (token %s)", actorID, user.ID, claims.ID)
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"accessToken": accessToken,
+		"expiresAt":   claims.ExpiresAt.Time.Format(time.RFC3339),
+		"persona":     persona,
+		"user":        user,
+	}, "Impersonation token issued"))
+}
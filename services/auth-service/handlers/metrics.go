@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Domain metrics, served on /metrics next to the shared HTTP and pool metrics
+
+var (
+	otpSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "margwa_otp_sent_total",
+		Help: "OTPs handed to the SMS or email provider, by channel, purpose and result (delivered, failed).",
+	}, []string{"channel", "purpose", "result"})
+
+	otpVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "margwa_otp_verification_failures_total",
+		Help: "Rejected OTP submissions, by purpose and reason (invalid, expired, too_many_attempts).",
+	}, []string{"purpose", "reason"})
+)
@@ -0,0 +1,647 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+	"margwa/auth-service/ratelimit"
+	"margwa/auth-service/totp"
+	"margwa/auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	errNoSecondFactor      = errors.New("no second factor enrolled")
+	errInvalidSecondFactor = errors.New("invalid second factor code")
+)
+
+// authMethods lists the amr claim values (RFC 8176) for a session. Every
+// login proves a one-time code; "mfa" marks a session that has also passed
+// TOTP or a recovery code.
+func authMethods(mfaVerifiedAt *time.Time) []string {
+	if mfaVerifiedAt != nil {
+		return []string{"otp", "mfa"}
+	}
+	return []string{"otp"}
+}
+
+// checkSecondFactor verifies code against the user's confirmed TOTP secret,
+// or, when it is not a 6-digit code, against their unused recovery codes.
+// It returns the method that matched: "totp" or "recovery_code". The code is
+// consumed in tx, so it stays usable if the caller's transaction rolls back.
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, tx pgx.Tx, userID, code string) (string, error) {
+	var sealed string
+	var lastCounter int64
+	err := tx.QueryRow(ctx,
+		`SELECT secret_encrypted, last_used_counter FROM user_totp
+		 WHERE user_id = $1 AND confirmed_at IS NOT NULL`,
+		userID,
+	).Scan(&sealed, &lastCounter)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", errNoSecondFactor
+	}
+	if err != nil {
+		return "", err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != totp.Digits {
+		return h.useRecoveryCode(ctx, tx, userID, code)
+	}
+
+	secret, err := h.totpSealer.Open(userID, sealed)
+	if err != nil {
+		return "", fmt.Errorf("open totp secret: %w", err)
+	}
+	counter, ok := totp.Validate(secret, code, time.Now(), lastCounter)
+	if !ok {
+		return "", errInvalidSecondFactor
+	}
+
+	// Only one request may consume a time step
+	tag, err := tx.Exec(ctx,
+		"UPDATE user_totp SET last_used_counter = $1 WHERE user_id = $2 AND last_used_counter < $1",
+		counter, userID,
+	)
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() == 0 {
+		return "", errInvalidSecondFactor
+	}
+	return "totp", nil
+}
+
+func (h *AuthHandler) useRecoveryCode(ctx context.Context, tx pgx.Tx, userID, code string) (string, error) {
+	code = normalizeRecoveryCode(code)
+
+	rows, err := tx.Query(ctx,
+		"SELECT id, code_hash FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		userID,
+	)
+	if err != nil {
+		return "", err
+	}
+	var match *uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return "", err
+		}
+		if match == nil && utils.VerifyOTPHash(h.config.OTPSecret, id.String(), code, hash) {
+			match = &id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if match == nil {
+		return "", errInvalidSecondFactor
+	}
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE mfa_recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL",
+		*match,
+	)
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() == 0 {
+		return "", errInvalidSecondFactor
+	}
+	return "recovery_code", nil
+}
+
+// allowSecondFactorAttempt applies the OTP verification limits to TOTP and
+// recovery codes, keyed by user
+func (h *AuthHandler) allowSecondFactorAttempt(c *gin.Context, userID string) bool {
+	ctx := tracing.Context(c)
+
+	wait, err := h.limiter.Cooldown(ctx, "mfa:"+userID)
+	if err == nil && wait == 0 {
+		var allowed bool
+		allowed, wait, err = h.limiter.Allow(ctx, ratelimit.Rule{
+			Key:    "mfa:user:" + userID,
+			Limit:  h.config.OTPVerifyLimitPerPhone,
+			Window: h.config.OTPVerifyWindow,
+		})
+		if allowed {
+			wait = 0
+		}
+	}
+	if err != nil {
+		logging.Warnf(ctx, "Rate limiter unavailable: %v", err)
+		return true
+	}
+	if wait > 0 {
+		rejectRateLimited(c, wait)
+		return false
+	}
+	return true
+}
+
+// rejectSecondFactor responds to a failed checkSecondFactor. field names the
+// request field holding the code.
+func (h *AuthHandler) rejectSecondFactor(c *gin.Context, userID string, deviceID *string, field string, err error) {
+	switch {
+	case errors.Is(err, errNoSecondFactor):
+		c.JSON(http.StatusBadRequest, response.Error("MFA_NOT_ENROLLED", "No authenticator app is enrolled", nil))
+	case errors.Is(err, errInvalidSecondFactor):
+		if err := h.limiter.RecordFailure(tracing.Context(c), "mfa:"+userID); err != nil {
+			logging.Errorf(tracing.Context(c), "Error recording MFA failure: %v", err)
+		}
+		h.recordEvent(c, userID, audit.EventOTPFailed, deviceID, map[string]interface{}{
+			"purpose": "mfa",
+		})
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_MFA_CODE", "Invalid authenticator or recovery code", gin.H{"field": field}))
+	default:
+		logging.Errorf(tracing.Context(c), "Error checking second factor for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify code", nil))
+	}
+}
+
+// requireSession returns the caller's session ID, or responds with
+// SESSION_UNKNOWN and returns false for tokens issued before access tokens
+// carried one
+func requireSession(c *gin.Context) (string, bool) {
+	sessionID := c.GetString("sessionId")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, response.Error("SESSION_UNKNOWN", "Current session could not be identified, please log in again", nil))
+		return "", false
+	}
+	return sessionID, true
+}
+
+// issueSessionAccessToken mints an access token for the caller's session
+// reflecting its current persona and MFA state
+func (h *AuthHandler) issueSessionAccessToken(ctx context.Context, userID, sessionID string) (string, error) {
+	var user models.User
+	session := tokenSession{ID: sessionID}
+	err := h.db.QueryRow(ctx,
+		`SELECT u.id, u.phone_number, u.user_type, s.persona, s.mfa_verified_at
+		 FROM users u JOIN sessions s ON s.user_id = u.id
+		 WHERE u.id = $1 AND s.id = $2`,
+		userID, sessionID,
+	).Scan(&user.ID, &user.PhoneNumber, &user.UserType, &session.Persona, &session.MFAVerifiedAt)
+	if err != nil {
+		return "", err
+	}
+	return h.generateAccessToken(&user, session)
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and stores a new
+// set, returning the plaintext codes to show once
+func (h *AuthHandler) replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string) ([]string, error) {
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		id := uuid.New()
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)",
+			id, userID, utils.HashOTP(h.config.OTPSecret, id.String(), normalizeRecoveryCode(code)),
+		); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k7q2-m9xa"
+func generateRecoveryCode() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	raw := make([]byte, 8)
+	for i := range raw {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		raw[i] = alphabet[n.Int64()]
+	}
+	return string(raw[:4]) + "-" + string(raw[4:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// MFAStatus reports whether TOTP is enrolled and whether the current session
+// has passed it
+func (h *AuthHandler) MFAStatus(c *gin.Context) {
+	userID := c.GetString("userId")
+	ctx := tracing.Context(c)
+
+	status := models.MFAStatus{}
+	err := h.db.QueryRow(ctx,
+		"SELECT confirmed_at FROM user_totp WHERE user_id = $1 AND confirmed_at IS NOT NULL",
+		userID,
+	).Scan(&status.EnrolledAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logging.Errorf(ctx, "Error loading MFA status: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to load MFA status", nil))
+		return
+	}
+	status.TOTPEnabled = status.EnrolledAt != nil
+
+	if status.TOTPEnabled {
+		h.db.QueryRow(ctx,
+			"SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+			userID,
+		).Scan(&status.RecoveryCodesRemaining)
+	}
+	h.db.QueryRow(ctx,
+		"SELECT mfa_verified_at IS NOT NULL FROM sessions WHERE id = $1 AND user_id = $2",
+		c.GetString("sessionId"), userID,
+	).Scan(&status.SessionVerified)
+
+	c.JSON(http.StatusOK, response.Success(status, "MFA status retrieved successfully"))
+}
+
+// EnrollTOTP creates a pending TOTP secret. It takes effect once ConfirmTOTP
+// receives a code generated from it.
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	userID := c.GetString("userId")
+	ctx := tracing.Context(c)
+
+	var confirmed bool
+	err := h.db.QueryRow(ctx,
+		"SELECT confirmed_at IS NOT NULL FROM user_totp WHERE user_id = $1",
+		userID,
+	).Scan(&confirmed)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logging.Errorf(ctx, "Error loading TOTP enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to start enrollment", nil))
+		return
+	}
+	if confirmed {
+		c.JSON(http.StatusConflict, response.Error("MFA_ALREADY_ENABLED", "An authenticator app is already enrolled", nil))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logging.Errorf(ctx, "Error generating TOTP secret: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to start enrollment", nil))
+		return
+	}
+	sealed, err := h.totpSealer.Seal(userID, secret)
+	if err != nil {
+		logging.Errorf(ctx, "Error sealing TOTP secret: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to start enrollment", nil))
+		return
+	}
+
+	_, err = h.db.Exec(ctx,
+		`INSERT INTO user_totp (user_id, secret_encrypted)
+		 VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_counter = 0, created_at = NOW()
+		 WHERE user_totp.confirmed_at IS NULL`,
+		userID, sealed,
+	)
+	if err != nil {
+		logging.Errorf(ctx, "Error storing TOTP secret: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to start enrollment", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(models.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.ProvisioningURI(h.config.TOTPIssuer, c.GetString("phoneNumber"), secret),
+	}, "Scan the QR code with your authenticator app, then confirm with a code"))
+}
+
+// ConfirmTOTP activates a pending TOTP secret, returns the recovery codes
+// and marks the current session as having passed MFA
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	userID := c.GetString("userId")
+	sessionID, ok := requireSession(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+	if !h.allowSecondFactorAttempt(c, userID) {
+		return
+	}
+
+	ctx := tracing.Context(c)
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var sealed string
+	err = tx.QueryRow(ctx,
+		"SELECT secret_encrypted FROM user_totp WHERE user_id = $1 AND confirmed_at IS NULL FOR UPDATE",
+		userID,
+	).Scan(&sealed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, response.Error("ENROLLMENT_NOT_FOUND", "Start enrollment first", nil))
+		return
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error loading TOTP enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+
+	secret, err := h.totpSealer.Open(userID, sealed)
+	if err != nil {
+		logging.Errorf(ctx, "Error opening TOTP secret for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+	counter, ok := totp.Validate(secret, req.Code, time.Now(), 0)
+	if !ok {
+		h.rejectSecondFactor(c, userID, nil, "code", errInvalidSecondFactor)
+		return
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE user_totp SET confirmed_at = NOW(), last_used_counter = $1 WHERE user_id = $2",
+		counter, userID,
+	); err != nil {
+		logging.Errorf(ctx, "Error confirming TOTP: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+
+	codes, err := h.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		logging.Errorf(ctx, "Error storing recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE sessions SET mfa_verified_at = NOW() WHERE id = $1 AND user_id = $2",
+		sessionID, userID,
+	); err != nil {
+		logging.Errorf(ctx, "Error marking session MFA: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.Errorf(ctx, "Error committing TOTP enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to confirm enrollment", nil))
+		return
+	}
+
+	h.recordEvent(c, userID, audit.EventMFAEnrolled, nil, map[string]interface{}{
+		"method": "totp",
+	})
+
+	accessToken, err := h.issueSessionAccessToken(ctx, userID, sessionID)
+	if err != nil {
+		logging.Errorf(ctx, "Error generating access token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"recoveryCodes": codes,
+		"accessToken":   accessToken,
+		"expiresIn":     env.FormatDuration(h.config.JWTExpiresIn),
+	}, "Authenticator app enrolled. Store the recovery codes somewhere safe."))
+}
+
+// VerifyMFA marks the current session as having passed the second factor and
+// returns an access token carrying the "mfa" amr value
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	userID := c.GetString("userId")
+	sessionID, ok := requireSession(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+	if !h.allowSecondFactorAttempt(c, userID) {
+		return
+	}
+
+	ctx := tracing.Context(c)
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify code", nil))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	method, err := h.checkSecondFactor(ctx, tx, userID, req.Code)
+	if err != nil {
+		h.rejectSecondFactor(c, userID, nil, "code", err)
+		return
+	}
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE sessions SET mfa_verified_at = NOW() WHERE id = $1 AND user_id = $2",
+		sessionID, userID,
+	)
+	if err == nil && tag.RowsAffected() == 0 {
+		c.JSON(http.StatusUnauthorized, response.Error("SESSION_REVOKED", "Session is no longer active, please log in again", nil))
+		return
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error marking session MFA: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify code", nil))
+		return
+	}
+	if err := h.limiter.ResetFailures(ctx, "mfa:"+userID); err != nil {
+		logging.Errorf(ctx, "Error resetting MFA failures: %v", err)
+	}
+
+	h.recordEvent(c, userID, audit.EventMFAVerified, nil, map[string]interface{}{
+		"method":    method,
+		"sessionId": sessionID,
+	})
+
+	accessToken, err := h.issueSessionAccessToken(ctx, userID, sessionID)
+	if err != nil {
+		logging.Errorf(ctx, "Error generating access token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"accessToken": accessToken,
+		"expiresIn":   env.FormatDuration(h.config.JWTExpiresIn),
+		"method":      method,
+	}, "Second factor verified"))
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking
+// the second factor
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+	if !h.allowSecondFactorAttempt(c, userID) {
+		return
+	}
+
+	ctx := tracing.Context(c)
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to regenerate recovery codes", nil))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := h.checkSecondFactor(ctx, tx, userID, req.Code); err != nil {
+		h.rejectSecondFactor(c, userID, nil, "code", err)
+		return
+	}
+
+	codes, err := h.replaceRecoveryCodes(ctx, tx, userID)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error regenerating recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to regenerate recovery codes", nil))
+		return
+	}
+
+	h.recordEvent(c, userID, audit.EventRecoveryCodesRegenerated, nil, nil)
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"recoveryCodes": codes,
+	}, "Recovery codes regenerated. Previous codes no longer work."))
+}
+
+// DisableTOTP removes the authenticator app after checking the second factor.
+// Every session loses its MFA state, and access tokens issued before are
+// revoked.
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID := c.GetString("userId")
+	sessionID, ok := requireSession(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+	if !h.allowSecondFactorAttempt(c, userID) {
+		return
+	}
+
+	ctx := tracing.Context(c)
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to disable MFA", nil))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := h.checkSecondFactor(ctx, tx, userID, req.Code); err != nil {
+		h.rejectSecondFactor(c, userID, nil, "code", err)
+		return
+	}
+
+	for _, stmt := range []string{
+		"DELETE FROM user_totp WHERE user_id = $1",
+		"DELETE FROM mfa_recovery_codes WHERE user_id = $1",
+	} {
+		if _, err = tx.Exec(ctx, stmt, userID); err != nil {
+			break
+		}
+	}
+	var sessionIDs []string
+	if err == nil {
+		var rows pgx.Rows
+		rows, err = tx.Query(ctx,
+			"UPDATE sessions SET mfa_verified_at = NULL WHERE user_id = $1 RETURNING id::text",
+			userID,
+		)
+		if err == nil {
+			sessionIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		}
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error disabling MFA for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to disable MFA", nil))
+		return
+	}
+
+	// Access tokens already issued may still carry the "mfa" amr value. Other
+	// sessions refresh into tokens without it; this one gets a new token below.
+	others := slices.DeleteFunc(sessionIDs, func(id string) bool { return id == sessionID })
+	err = h.tokens.RevokeSessions(ctx, others...)
+	if err == nil {
+		err = h.tokens.RevokeToken(ctx, c.GetString("tokenId"), c.GetTime("tokenExpiresAt"))
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error revoking access tokens for user %s: %v", userID, err)
+	}
+
+	h.recordEvent(c, userID, audit.EventMFADisabled, nil, nil)
+
+	accessToken, err := h.issueSessionAccessToken(ctx, userID, sessionID)
+	if err != nil {
+		logging.Errorf(ctx, "Error generating access token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"accessToken": accessToken,
+		"expiresIn":   env.FormatDuration(h.config.JWTExpiresIn),
+	}, "Authenticator app removed"))
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"margwa/auth-service/utils"
+
+	"github.com/google/uuid"
+	"github.com/margwa/shared/go/logging"
+)
+
+// errOTPDelivery is returned by issueOTP when the SMS or mail provider rejects the message
+var errOTPDelivery = errors.New("otp delivery failed")
+
+type issuedOTP struct {
+	ID        uuid.UUID
+	Code      string
+	ExpiresAt time.Time
+}
+
+// issueOTP stores a hashed OTP for the phone number and delivers the code over
+// channel: "sms" texts it to the number and "email" mails it to the given
+// address. purpose keeps codes for different flows from being accepted by
+// each other.
+func (h *AuthHandler) issueOTP(ctx context.Context, userID uuid.UUID, phone, purpose, channel, email string) (*issuedOTP, error) {
+	otpCode, err := utils.GenerateOTP(h.config.OTPLength)
+	if err != nil {
+		return nil, fmt.Errorf("generate otp: %w", err)
+	}
+
+	otp := &issuedOTP{
+		ID:        uuid.New(),
+		Code:      otpCode,
+		ExpiresAt: time.Now().Add(time.Duration(h.config.OTPExpiryMinutes) * time.Minute),
+	}
+
+	_, err = h.db.Exec(context.WithoutCancel(ctx),
+		`INSERT INTO otp_verifications (id, user_id, phone_number, otp_code, purpose, delivery_channel, expires_at, attempts)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, 0)`,
+		otp.ID, userID, phone, utils.HashOTP(h.config.OTPSecret, otp.ID.String(), otpCode), purpose, channel, otp.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("store otp: %w", err)
+	}
+
+	message := fmt.Sprintf("Your Margwa verification code is %s. It expires in %d minutes.", otpCode, h.config.OTPExpiryMinutes)
+	if channel == "email" {
+		err = h.mail.Send(ctx, email, "Your Margwa verification code", message)
+	} else {
+		err = h.sms.Send(ctx, phone, message)
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error sending OTP %s: %v", otp.ID, err)
+		otpSent.WithLabelValues(channel, purpose, "failed").Inc()
+		h.db.Exec(context.WithoutCancel(ctx),
+			"UPDATE otp_verifications SET delivery_status = 'failed', delivery_error = $1 WHERE id = $2",
+			err.Error(), otp.ID,
+		)
+		return nil, errOTPDelivery
+	}
+
+	h.db.Exec(context.WithoutCancel(ctx),
+		"UPDATE otp_verifications SET delivery_status = 'sent' WHERE id = $1",
+		otp.ID,
+	)
+	otpSent.WithLabelValues(channel, purpose, "delivered").Inc()
+
+	return otp, nil
+}
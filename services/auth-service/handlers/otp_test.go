@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSendOTPReturnsCodeOnlyOutsideProduction(t *testing.T) {
+	for _, tc := range []struct {
+		environment string
+		wantCode    bool
+	}{
+		{"development", true},
+		{"production", false},
+	} {
+		t.Run(tc.environment, func(t *testing.T) {
+			s := newTestServer(t, tc.environment)
+			number := s.registerUser(t)
+
+			status, env := s.do(t, http.MethodPost, "/auth/send-otp", "", gin.H{
+				"phoneNumber": number, "phoneCountryCode": "+91",
+			})
+			if status != http.StatusOK {
+				t.Fatalf("status %d, body %+v", status, env)
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal(env.Data, &data); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := data["otp"]; ok != tc.wantCode {
+				t.Errorf("otp in response = %v, want %v", ok, tc.wantCode)
+			}
+			if _, ok := s.sms.LastMessageTo("+91" + number); !ok {
+				t.Error("no SMS delivered")
+			}
+		})
+	}
+}
+
+func TestVerifyOTPConcurrent(t *testing.T) {
+	const parallel = 8
+
+	s := newTestServer(t, "development")
+
+	// verify fires parallel requests for one code and counts the outcomes
+	verify := func(number, code string) map[string]int {
+		results := make(chan string, parallel)
+		var wg sync.WaitGroup
+		for i := 0; i < parallel; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status, env := s.do(t, http.MethodPost, "/auth/verify-otp", "", gin.H{
+					"phoneNumber": number, "phoneCountryCode": "+91", "otpCode": code,
+				})
+				switch {
+				case status == http.StatusOK:
+					results <- "OK"
+				case env.Error != nil:
+					results <- env.Error.Code
+				default:
+					results <- http.StatusText(status)
+				}
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		counts := map[string]int{}
+		for r := range results {
+			counts[r]++
+		}
+		return counts
+	}
+
+	t.Run("same code logs in once", func(t *testing.T) {
+		number := s.registerUser(t)
+		code := s.sendOTP(t, number)
+
+		counts := verify(number, code)
+		if counts["OK"] != 1 {
+			t.Errorf("%d logins from one code, want 1 (outcomes %v)", counts["OK"], counts)
+		}
+	})
+
+	t.Run("wrong codes cannot exceed the attempt limit", func(t *testing.T) {
+		number := s.registerUser(t)
+		code := s.sendOTP(t, number)
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+
+		counts := verify(number, wrong)
+		if counts["INVALID_OTP"] != 3 || counts["TOO_MANY_ATTEMPTS"] != parallel-3 {
+			t.Errorf("outcomes %v, want 3 INVALID_OTP and the rest TOO_MANY_ATTEMPTS", counts)
+		}
+
+		// The right code no longer works either
+		status, env := s.do(t, http.MethodPost, "/auth/verify-otp", "", gin.H{
+			"phoneNumber": number, "phoneCountryCode": "+91", "otpCode": code,
+		})
+		if status == http.StatusOK || env.Error == nil || env.Error.Code != "TOO_MANY_ATTEMPTS" {
+			t.Errorf("correct code after lockout: status %d, body %+v, want TOO_MANY_ATTEMPTS", status, env)
+		}
+	})
+}
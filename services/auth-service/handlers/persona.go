@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+	"margwa/auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// resolvePersona picks the app a session acts in. Clients that do not say
+// get auth.DefaultPersona. It responds with
+// PERSONA_NOT_AVAILABLE and returns false when userType does not include
+// the requested persona.
+func resolvePersona(c *gin.Context, userType string, requested *string) (string, bool) {
+	if requested == nil || *requested == "" {
+		return auth.DefaultPersona(userType), true
+	}
+
+	if userType != "both" && userType != *requested {
+		c.JSON(http.StatusForbidden, response.Error("PERSONA_NOT_AVAILABLE", "Your account is not registered for this app", gin.H{
+			"userType": userType,
+			"persona":  *requested,
+		}))
+		return "", false
+	}
+	return *requested, true
+}
+
+// SwitchPersona moves the current session to the other app for users
+// registered in both. The refresh token is rotated, and every access token
+// the session issued so far is revoked so none keeps acting as the old
+// persona.
+func (h *AuthHandler) SwitchPersona(c *gin.Context) {
+	userID := c.GetString("userId")
+	sessionID := c.GetString("sessionId")
+	if sessionID == "" {
+		c.JSON(http.StatusUnauthorized, response.Error("SESSION_UNKNOWN", "Current session could not be identified, please log in again", nil))
+		return
+	}
+
+	var req models.SwitchPersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	ctx := tracing.Context(c)
+	var user models.User
+	var session models.Session
+	err := h.db.QueryRow(ctx,
+		`SELECT u.id, u.phone_number, u.user_type, s.id, s.device_id, s.persona, s.mfa_verified_at
+		 FROM users u JOIN sessions s ON s.user_id = u.id
+		 WHERE u.id = $1 AND s.id = $2 AND s.expires_at > NOW()`,
+		userID, sessionID,
+	).Scan(&user.ID, &user.PhoneNumber, &user.UserType, &session.ID, &session.DeviceID, &session.Persona, &session.MFAVerifiedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusUnauthorized, response.Error("SESSION_EXPIRED", "Session has expired, please log in again", nil))
+		return
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error loading session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to switch persona", nil))
+		return
+	}
+
+	persona, ok := resolvePersona(c, user.UserType, &req.Persona)
+	if !ok {
+		return
+	}
+
+	refreshTokenDuration := h.config.JWTRefreshExpires
+
+	// Revoke first: the new access token is dated after the revocation
+	if err := h.tokens.RevokeSessions(ctx, sessionID); err != nil {
+		logging.Errorf(ctx, "Error revoking access tokens for session %s: %v", sessionID, err)
+	}
+
+	accessToken, err := h.generateAccessToken(&user, tokenSession{
+		ID:            sessionID,
+		Persona:       persona,
+		MFAVerifiedAt: session.MFAVerifiedAt,
+	})
+	if err != nil {
+		logging.Errorf(ctx, "Error generating access token: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate access token", nil))
+		return
+	}
+
+	refreshToken, err := utils.GenerateJWT(&user, sessionID, h.config.JWTRefreshSecret, refreshTokenDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to generate refresh token", nil))
+		return
+	}
+
+	_, err = h.db.Exec(ctx,
+		`UPDATE sessions
+		 SET persona = $1, refresh_token = $2, expires_at = $3, last_used_at = NOW(), ip_address = $4
+		 WHERE id = $5 AND user_id = $6`,
+		persona, refreshToken, time.Now().Add(refreshTokenDuration), c.ClientIP(), sessionID, userID,
+	)
+	if err != nil {
+		logging.Errorf(ctx, "Error switching persona for session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to switch persona", nil))
+		return
+	}
+
+	h.recordEvent(c, userID, audit.EventPersonaSwitch, session.DeviceID, map[string]interface{}{
+		"sessionId": sessionID,
+		"from":      session.Persona,
+		"to":        persona,
+	})
+
+	c.JSON(http.StatusOK, response.Success(models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    env.FormatDuration(h.config.JWTExpiresIn),
+		Persona:      persona,
+	}, "Persona switched successfully"))
+}
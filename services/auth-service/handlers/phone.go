@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+	"margwa/auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint failure
+const uniqueViolation = "23505"
+
+// StartPhoneChange texts a code to both the current and the new phone number
+func (h *AuthHandler) StartPhoneChange(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	var req models.StartPhoneChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	newPhone, _, err := utils.NormalizePhoneNumber(req.NewPhoneCountryCode, req.NewPhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_PHONE_NUMBER", "Invalid phone number", err.Error()))
+		return
+	}
+
+	var uid uuid.UUID
+	var currentPhone string
+	var newPhoneTaken bool
+	err = h.db.QueryRow(tracing.Context(c),
+		`SELECT id, phone_number, EXISTS (SELECT 1 FROM users WHERE phone_number = $2)
+		 FROM users WHERE id = $1`,
+		userID, newPhone,
+	).Scan(&uid, &currentPhone, &newPhoneTaken)
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+		return
+	}
+
+	if newPhone == currentPhone {
+		c.JSON(http.StatusBadRequest, response.Error("SAME_PHONE_NUMBER", "New phone number is the same as the current one", nil))
+		return
+	}
+	if newPhoneTaken {
+		c.JSON(http.StatusConflict, response.Error("PHONE_NUMBER_IN_USE", "Phone number is already registered to another account", nil))
+		return
+	}
+
+	if !h.enforceOTPLimits(c, "send", currentPhone, nil) || !h.enforceOTPLimits(c, "send", newPhone, nil) {
+		return
+	}
+
+	data := gin.H{}
+	for _, target := range []struct{ field, phone string }{
+		{"old", currentPhone},
+		{"new", newPhone},
+	} {
+		otp, err := h.issueOTP(c.Request.Context(), uid, target.phone, "phone_change", "sms", "")
+		if errors.Is(err, errOTPDelivery) {
+			c.JSON(http.StatusBadGateway, response.Error("OTP_DELIVERY_FAILED", "Failed to deliver OTP, please try again", gin.H{
+				"phone": target.field,
+			}))
+			return
+		}
+		if err != nil {
+			logging.Errorf(tracing.Context(c), "Error issuing phone change OTP: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("INTERNAL_ERROR", "Failed to issue OTP", nil))
+			return
+		}
+
+		data[target.field+"OtpId"] = otp.ID
+		data["expiresAt"] = otp.ExpiresAt
+		// Outside production, return OTP in response
+		if h.config.Environment != "production" {
+			data[target.field+"Otp"] = otp.Code
+		}
+	}
+
+	c.JSON(http.StatusOK, response.Success(data, "OTP sent to both phone numbers"))
+}
+
+// ConfirmPhoneChange checks the codes sent to both numbers, moves the account
+// to the new number and signs out every device
+func (h *AuthHandler) ConfirmPhoneChange(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	var req models.ConfirmPhoneChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	newPhone, newCountryCode, err := utils.NormalizePhoneNumber(req.NewPhoneCountryCode, req.NewPhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_PHONE_NUMBER", "Invalid phone number", err.Error()))
+		return
+	}
+
+	var currentPhone string
+	err = h.db.QueryRow(tracing.Context(c),
+		"SELECT phone_number FROM users WHERE id = $1",
+		userID,
+	).Scan(&currentPhone)
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+		return
+	}
+
+	if !h.enforceOTPLimits(c, "verify", currentPhone, nil) || !h.enforceOTPLimits(c, "verify", newPhone, nil) {
+		return
+	}
+
+	ctx := tracing.Context(c)
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		logging.Errorf(ctx, "Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock and check both codes, the current number's first
+	var otpIDs []uuid.UUID
+	for _, target := range []struct{ field, phone, code string }{
+		{"oldOtpCode", currentPhone, req.OldOTPCode},
+		{"newOtpCode", newPhone, req.NewOTPCode},
+	} {
+		var otp models.OTPVerification
+		err = tx.QueryRow(ctx,
+			`SELECT id, otp_code, expires_at, attempts
+			 FROM otp_verifications
+			 WHERE user_id = $1 AND phone_number = $2 AND purpose = 'phone_change'
+			   AND verified_at IS NULL AND delivery_status <> 'failed'
+			 ORDER BY created_at DESC LIMIT 1
+			 FOR UPDATE`,
+			userID, target.phone,
+		).Scan(&otp.ID, &otp.OTPCode, &otp.ExpiresAt, &otp.Attempts)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, response.Error("OTP_NOT_FOUND", "No valid OTP found", gin.H{"field": target.field}))
+			return
+		}
+		if err != nil {
+			logging.Errorf(ctx, "Error loading phone change OTP: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+			return
+		}
+
+		if time.Now().After(otp.ExpiresAt) {
+			otpVerificationFailures.WithLabelValues("phone_change", "expired").Inc()
+			c.JSON(http.StatusBadRequest, response.Error("OTP_EXPIRED", "OTP has expired", gin.H{"field": target.field}))
+			return
+		}
+
+		if otp.Attempts >= 3 {
+			otpVerificationFailures.WithLabelValues("phone_change", "too_many_attempts").Inc()
+			c.JSON(http.StatusBadRequest, response.Error("TOO_MANY_ATTEMPTS", "Too many failed attempts", gin.H{"field": target.field}))
+			return
+		}
+
+		if !utils.VerifyOTPHash(h.config.OTPSecret, otp.ID.String(), target.code, otp.OTPCode) {
+			// Increment attempts and commit so the failure sticks
+			_, err = tx.Exec(ctx,
+				"UPDATE otp_verifications SET attempts = attempts + 1 WHERE id = $1",
+				otp.ID,
+			)
+			if err == nil {
+				err = tx.Commit(ctx)
+			}
+			if err != nil {
+				logging.Errorf(ctx, "Error recording OTP attempt: %v", err)
+				c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+				return
+			}
+
+			if err := h.limiter.RecordFailure(ctx, otpSubjects(c, target.phone)...); err != nil {
+				logging.Errorf(ctx, "Error recording OTP failure: %v", err)
+			}
+			otpVerificationFailures.WithLabelValues("phone_change", "invalid").Inc()
+			c.JSON(http.StatusBadRequest, response.Error("INVALID_OTP", "Invalid OTP code", gin.H{"field": target.field}))
+			return
+		}
+
+		otpIDs = append(otpIDs, otp.ID)
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE otp_verifications SET verified_at = NOW() WHERE id = ANY($1)",
+		otpIDs,
+	); err != nil {
+		logging.Errorf(ctx, "Error marking OTPs verified: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE users SET phone_number = $1, phone_country_code = $2, updated_at = NOW()
+		 WHERE id = $3`,
+		newPhone, newCountryCode, userID,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		c.JSON(http.StatusConflict, response.Error("PHONE_NUMBER_IN_USE", "Phone number is already registered to another account", nil))
+		return
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error updating phone number: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		logging.Errorf(ctx, "Error revoking sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.Errorf(ctx, "Error committing phone change: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to change phone number", nil))
+		return
+	}
+
+	// Access tokens still carry the old number, so revoke them too
+	if err := h.tokens.RevokeUser(ctx, userID); err != nil {
+		logging.Errorf(ctx, "Error denylisting tokens for %s: %v", userID, err)
+	}
+	if err := h.limiter.ResetFailures(ctx, "phone:"+currentPhone, "phone:"+newPhone); err != nil {
+		logging.Errorf(ctx, "Error resetting OTP failures: %v", err)
+	}
+
+	h.recordEvent(c, userID, audit.EventPhoneChange, nil, map[string]interface{}{
+		"from": currentPhone,
+		"to":   newPhone,
+	})
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"phoneNumber":      newPhone,
+		"phoneCountryCode": newCountryCode,
+	}, "Phone number changed. Please log in again."))
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+	"margwa/auth-service/notify"
+	"margwa/auth-service/risk"
+	"margwa/auth-service/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// assessLogin scores a login about to be created. Failures are logged and
+// the login goes ahead unscored rather than locking users out.
+func (h *AuthHandler) assessLogin(c *gin.Context, userID string, req *models.VerifyOTPRequest) *risk.Assessment {
+	login := risk.Login{
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
+	if req.DeviceID != nil {
+		login.DeviceID = *req.DeviceID
+	}
+
+	assessment, err := h.risk.Assess(tracing.Context(c), login)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error assessing login risk for user %s: %v", userID, err)
+		return nil
+	}
+	return assessment
+}
+
+// requiresStepUp reports whether a login must pass a second factor
+func (h *AuthHandler) requiresStepUp(assessment *risk.Assessment) bool {
+	return h.config.RiskStepUpEnabled && assessment != nil && assessment.AtLeast(risk.LevelHigh)
+}
+
+// verifyStepUp checks the second factor for a high-risk login inside the
+// login transaction. A login without stepUpCode gets a STEP_UP_REQUIRED
+// error and the client retries with the same SMS code plus stepUpCode.
+// Users with an authenticator app enter a TOTP or recovery code; otherwise a
+// one-time code is mailed to their verified address. Users with neither are
+// let through. It returns the method used, or false after responding.
+func (h *AuthHandler) verifyStepUp(c *gin.Context, tx pgx.Tx, user *models.User, req *models.VerifyOTPRequest, assessment *risk.Assessment) (string, bool) {
+	ctx := tracing.Context(c)
+
+	var totpEnabled bool
+	err := tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id = $1 AND confirmed_at IS NOT NULL)",
+		user.ID,
+	).Scan(&totpEnabled)
+	if err != nil {
+		logging.Errorf(ctx, "Error loading TOTP enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+		return "", false
+	}
+
+	if totpEnabled {
+		if req.StepUpCode == nil || *req.StepUpCode == "" {
+			c.JSON(http.StatusUnauthorized, response.Error("STEP_UP_REQUIRED", "Additional verification is required for this login", gin.H{
+				"method":    "totp",
+				"riskLevel": assessment.Level,
+			}))
+			return "", false
+		}
+		if !h.allowSecondFactorAttempt(c, user.ID.String()) {
+			return "", false
+		}
+		method, err := h.checkSecondFactor(ctx, tx, user.ID.String(), *req.StepUpCode)
+		if err != nil {
+			h.rejectSecondFactor(c, user.ID.String(), req.DeviceID, "stepUpCode", err)
+			return "", false
+		}
+		return method, true
+	}
+
+	if user.Email == nil || user.EmailVerifiedAt == nil {
+		return "", true
+	}
+
+	if req.StepUpCode == nil || *req.StepUpCode == "" {
+		otp, err := h.issueOTP(ctx, user.ID, user.PhoneNumber, "step_up", "email", *user.Email)
+		if err != nil {
+			logging.Errorf(ctx, "Error issuing step-up code: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("OTP_DELIVERY_FAILED", "Failed to send verification code", nil))
+			return "", false
+		}
+		c.JSON(http.StatusUnauthorized, response.Error("STEP_UP_REQUIRED", "Additional verification is required for this login", gin.H{
+			"method":      "email",
+			"destination": utils.MaskEmail(*user.Email),
+			"expiresAt":   otp.ExpiresAt,
+			"riskLevel":   assessment.Level,
+		}))
+		return "", false
+	}
+
+	var otp models.OTPVerification
+	err = tx.QueryRow(ctx,
+		`SELECT id, otp_code, expires_at, attempts
+		 FROM otp_verifications
+		 WHERE user_id = $1 AND purpose = 'step_up'
+		   AND verified_at IS NULL AND delivery_status <> 'failed'
+		 ORDER BY created_at DESC LIMIT 1
+		 FOR UPDATE`,
+		user.ID,
+	).Scan(&otp.ID, &otp.OTPCode, &otp.ExpiresAt, &otp.Attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, response.Error("STEP_UP_NOT_FOUND", "No pending verification code, retry without stepUpCode", nil))
+		return "", false
+	}
+	if err != nil {
+		logging.Errorf(ctx, "Error loading step-up code: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+		return "", false
+	}
+
+	if time.Now().After(otp.ExpiresAt) {
+		otpVerificationFailures.WithLabelValues("step_up", "expired").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("OTP_EXPIRED", "Verification code has expired", gin.H{"field": "stepUpCode"}))
+		return "", false
+	}
+	if otp.Attempts >= 3 {
+		otpVerificationFailures.WithLabelValues("step_up", "too_many_attempts").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("TOO_MANY_ATTEMPTS", "Too many failed attempts", gin.H{"field": "stepUpCode"}))
+		return "", false
+	}
+
+	if !utils.VerifyOTPHash(h.config.OTPSecret, otp.ID.String(), *req.StepUpCode, otp.OTPCode) {
+		_, err = tx.Exec(ctx, "UPDATE otp_verifications SET attempts = attempts + 1 WHERE id = $1", otp.ID)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			logging.Errorf(ctx, "Error recording step-up attempt: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+			return "", false
+		}
+
+		if err := h.limiter.RecordFailure(ctx, otpSubjects(c, user.PhoneNumber)...); err != nil {
+			logging.Errorf(ctx, "Error recording OTP failure: %v", err)
+		}
+		h.recordEvent(c, user.ID.String(), audit.EventOTPFailed, req.DeviceID, map[string]interface{}{
+			"purpose": "step_up",
+			"attempt": otp.Attempts + 1,
+		})
+		otpVerificationFailures.WithLabelValues("step_up", "invalid").Inc()
+		c.JSON(http.StatusBadRequest, response.Error("INVALID_OTP", "Invalid verification code", gin.H{"field": "stepUpCode"}))
+		return "", false
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE otp_verifications SET verified_at = NOW() WHERE id = $1", otp.ID); err != nil {
+		logging.Errorf(ctx, "Error marking step-up code verified: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify OTP", nil))
+		return "", false
+	}
+
+	return "email", true
+}
+
+// notifyRiskyLogin tells the user about a login that scored medium risk or
+// higher. The alert is pushed to the user's most recently used other device
+// through notification-service, with an SMS when no device can be reached.
+// It outlives the request but keeps ctx's request and trace IDs for logging.
+func (h *AuthHandler) notifyRiskyLogin(ctx context.Context, user *models.User, sessionID string, assessment *risk.Assessment, deviceType *string, ip string) {
+	if assessment == nil || !assessment.AtLeast(risk.LevelMedium) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+
+		device := "a new device"
+		if deviceType != nil && *deviceType != "" {
+			device = "a new " + *deviceType + " device"
+		}
+		body := fmt.Sprintf("Your Margwa account was signed in from %s (IP %s) at %s. If this wasn't you, sign out other devices and contact support.",
+			device, ip, time.Now().UTC().Format("02 Jan 15:04 MST"))
+
+		var fcmToken string
+		err := h.db.QueryRow(ctx,
+			`SELECT fcm_token FROM sessions
+			 WHERE user_id = $1 AND id <> $2 AND fcm_token IS NOT NULL AND expires_at > NOW()
+			 ORDER BY COALESCE(last_used_at, created_at) DESC LIMIT 1`,
+			user.ID, sessionID,
+		).Scan(&fcmToken)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			slog.ErrorContext(ctx, "Error finding device for login alert", "user_id", user.ID.String(), "error", err)
+		}
+
+		err = h.notifier.Send(ctx, notify.Notification{
+			UserID:           user.ID.String(),
+			Title:            "New sign-in to your account",
+			Body:             body,
+			NotificationType: "security_alert",
+			Data: map[string]string{
+				"sessionId": sessionID,
+				"riskLevel": assessment.Level,
+				"reasons":   strings.Join(assessment.Reasons, ","),
+			},
+			DeviceToken: fcmToken,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending login alert", "user_id", user.ID.String(), "error", err)
+		}
+		if err == nil && fcmToken != "" {
+			return
+		}
+
+		if err := h.sms.Send(ctx, user.PhoneNumber, body); err != nil {
+			slog.ErrorContext(ctx, "Error sending login alert SMS", "user_id", user.ID.String(), "error", err)
+		}
+	}()
+}
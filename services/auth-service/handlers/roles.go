@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// loadAccess fills in the user's roles and the permissions they grant
+func (h *AuthHandler) loadAccess(ctx context.Context, user *models.User) error {
+	return h.db.QueryRow(ctx,
+		`SELECT COALESCE(array_agg(DISTINCT r.name), '{}'),
+		        COALESCE(array_agg(DISTINCT p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		 FROM user_roles ur
+		 JOIN roles r ON r.id = ur.role_id
+		 LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		 LEFT JOIN permissions p ON p.id = rp.permission_id
+		 WHERE ur.user_id = $1`,
+		user.ID,
+	).Scan(&user.Roles, &user.Permissions)
+}
+
+// ListRoles returns every role with its permissions
+func (h *AuthHandler) ListRoles(c *gin.Context) {
+	rows, err := h.db.Query(tracing.Context(c),
+		`SELECT r.name, r.description,
+		        COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		 FROM roles r
+		 LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		 LEFT JOIN permissions p ON p.id = rp.permission_id
+		 GROUP BY r.id
+		 ORDER BY r.name`,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error listing roles: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to list roles", nil))
+		return
+	}
+	defer rows.Close()
+
+	roles := []models.RoleInfo{}
+	for rows.Next() {
+		var role models.RoleInfo
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			logging.Errorf(tracing.Context(c), "Error scanning role: %v", err)
+			continue
+		}
+		roles = append(roles, role)
+	}
+
+	c.JSON(http.StatusOK, response.Success(roles, "Roles retrieved successfully"))
+}
+
+// GrantRole gives a user a role. It shows up in their next access token.
+func (h *AuthHandler) GrantRole(c *gin.Context) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid user ID", nil))
+		return
+	}
+	grantedBy := c.GetString("userId")
+
+	var req models.GrantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	var roleID string
+	err = h.db.QueryRow(tracing.Context(c),
+		"SELECT id FROM roles WHERE name = $1",
+		req.Role,
+	).Scan(&roleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, response.Error("ROLE_NOT_FOUND", "Role not found", nil))
+		return
+	}
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error loading role: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to grant role", nil))
+		return
+	}
+
+	tag, err := h.db.Exec(tracing.Context(c),
+		`INSERT INTO user_roles (user_id, role_id, granted_by)
+		 SELECT id, $2, $3 FROM users WHERE id = $1 AND deleted_at IS NULL
+		 ON CONFLICT (user_id, role_id) DO NOTHING`,
+		targetID, roleID, grantedBy,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error granting role: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to grant role", nil))
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		h.db.QueryRow(tracing.Context(c),
+			"SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)",
+			targetID,
+		).Scan(&exists)
+		if !exists {
+			c.JSON(http.StatusNotFound, response.Error("USER_NOT_FOUND", "User not found", nil))
+			return
+		}
+	} else {
+		h.recordEvent(c, targetID.String(), audit.EventRoleGranted, nil, map[string]interface{}{
+			"role":      req.Role,
+			"grantedBy": grantedBy,
+		})
+	}
+
+	logging.Infof(tracing.Context(c), "User %s granted role %s to %s", grantedBy, req.Role, targetID)
+	c.JSON(http.StatusOK, response.Success(nil, "Role granted"))
+}
+
+// RevokeRole removes a role from a user and revokes their access tokens so
+// the change applies immediately; clients pick up the new roles on refresh
+func (h *AuthHandler) RevokeRole(c *gin.Context) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid user ID", nil))
+		return
+	}
+	role := c.Param("role")
+	revokedBy := c.GetString("userId")
+
+	tag, err := h.db.Exec(tracing.Context(c),
+		`DELETE FROM user_roles
+		 WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)`,
+		targetID, role,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error revoking role: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to revoke role", nil))
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, response.Error("ROLE_NOT_GRANTED", "User does not have this role", nil))
+		return
+	}
+
+	if err := h.tokens.RevokeUser(tracing.Context(c), targetID.String()); err != nil {
+		logging.Errorf(tracing.Context(c), "Error denylisting tokens for %s: %v", targetID, err)
+	}
+
+	h.recordEvent(c, targetID.String(), audit.EventRoleRevoked, nil, map[string]interface{}{
+		"role":      role,
+		"revokedBy": revokedBy,
+	})
+
+	logging.Infof(tracing.Context(c), "User %s revoked role %s from %s", revokedBy, role, targetID)
+	c.JSON(http.StatusOK, response.Success(nil, "Role revoked"))
+}
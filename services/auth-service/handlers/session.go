@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+
+	"margwa/auth-service/audit"
+	"margwa/auth-service/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// ListSessions returns the user's active sessions, one per signed-in device
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetString("userId")
+	currentSessionID := c.GetString("sessionId")
+
+	rows, err := h.db.Query(tracing.Context(c),
+		`SELECT id, device_id, device_type, ip_address, created_at, last_used_at, expires_at, persona, risk_level
+		 FROM sessions
+		 WHERE user_id = $1 AND expires_at > NOW()
+		 ORDER BY COALESCE(last_used_at, created_at) DESC`,
+		userID,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error listing sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to fetch sessions", nil))
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.SessionInfo{}
+	for rows.Next() {
+		var session models.SessionInfo
+		if err := rows.Scan(&session.ID, &session.DeviceID, &session.DeviceType, &session.IPAddress,
+			&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.Persona, &session.RiskLevel); err != nil {
+			logging.Errorf(tracing.Context(c), "Error scanning session: %v", err)
+			continue
+		}
+		session.Current = session.ID.String() == currentSessionID
+		sessions = append(sessions, session)
+	}
+
+	c.JSON(http.StatusOK, response.Success(sessions, "Sessions retrieved successfully"))
+}
+
+// RevokeSession signs out a single device
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString("userId")
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid session ID", nil))
+		return
+	}
+
+	tag, err := h.db.Exec(tracing.Context(c),
+		"DELETE FROM sessions WHERE id = $1 AND user_id = $2",
+		sessionID, userID,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error revoking session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to revoke session", nil))
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, response.Error("SESSION_NOT_FOUND", "Session not found", nil))
+		return
+	}
+
+	if err := h.tokens.RevokeSessions(tracing.Context(c), sessionID.String()); err != nil {
+		logging.Errorf(tracing.Context(c), "Error denylisting session %s: %v", sessionID, err)
+	}
+
+	h.recordEvent(c, userID, audit.EventSessionRevoked, nil, map[string]interface{}{
+		"sessionIds": []string{sessionID.String()},
+	})
+
+	c.JSON(http.StatusOK, response.Success(nil, "Session revoked successfully"))
+}
+
+// LogoutOthers revokes every session except the one making the request
+func (h *AuthHandler) LogoutOthers(c *gin.Context) {
+	userID := c.GetString("userId")
+	currentSessionID := c.GetString("sessionId")
+
+	if currentSessionID == "" {
+		c.JSON(http.StatusBadRequest, response.Error("SESSION_UNKNOWN", "Current session could not be identified, please log in again", nil))
+		return
+	}
+
+	rows, err := h.db.Query(tracing.Context(c),
+		"DELETE FROM sessions WHERE user_id = $1 AND id <> $2 RETURNING id",
+		userID, currentSessionID,
+	)
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error revoking other sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to revoke sessions", nil))
+		return
+	}
+	defer rows.Close()
+
+	var revokedIDs []string
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err == nil {
+			revokedIDs = append(revokedIDs, id.String())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logging.Errorf(tracing.Context(c), "Error revoking other sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to revoke sessions", nil))
+		return
+	}
+
+	if err := h.tokens.RevokeSessions(tracing.Context(c), revokedIDs...); err != nil {
+		logging.Errorf(tracing.Context(c), "Error denylisting sessions: %v", err)
+	}
+
+	if len(revokedIDs) > 0 {
+		h.recordEvent(c, userID, audit.EventSessionRevoked, nil, map[string]interface{}{
+			"sessionIds": revokedIDs,
+		})
+	}
+
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"revokedCount": len(revokedIDs),
+	}, "Other sessions revoked successfully"))
+}
@@ -0,0 +1,231 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one private key from the key directory
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+// KeySet holds the asymmetric keys used to sign access tokens. Each PEM file
+// in the key directory is one key and its file name (without .pem) is the
+// kid. The active key signs new tokens; every key in the set is published in
+// the JWKS so tokens signed before a rotation keep verifying until the old
+// key file is removed.
+type KeySet struct {
+	dir       string
+	activeKID string
+
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+// Load reads the key directory. An empty dir returns an empty set, in which
+// case access tokens stay on HS256.
+func Load(dir, activeKID string) (*KeySet, error) {
+	ks := &KeySet{dir: dir, activeKID: activeKID, keys: map[string]*signingKey{}}
+	if dir == "" {
+		return ks, nil
+	}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload re-reads the key directory, picking up added or removed keys
+func (ks *KeySet) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no signing keys found in %s", ks.dir)
+	}
+	sort.Strings(paths)
+
+	keys := make(map[string]*signingKey, len(paths))
+	var newest *signingKey
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return err
+		}
+		keys[key.kid] = key
+		newest = key
+	}
+
+	// Without an explicit JWT_ACTIVE_KID the last kid in sort order signs,
+	// so date-prefixed file names rotate naturally
+	active := newest
+	if ks.activeKID != "" {
+		var ok bool
+		if active, ok = keys[ks.activeKID]; !ok {
+			return fmt.Errorf("active signing key %q not found in %s", ks.activeKID, ks.dir)
+		}
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.active = active
+	ks.mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether asymmetric signing is configured
+func (ks *KeySet) Enabled() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active != nil
+}
+
+// Sign signs claims with the active key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	active := ks.active
+	ks.mu.RUnlock()
+
+	if active == nil {
+		return "", fmt.Errorf("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// Keyfunc verifies asymmetric tokens against the set and, when hmacSecret is
+// not empty, HS256 tokens against the shared secret
+func (ks *KeySet) Keyfunc(hmacSecret string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if hmacSecret == "" {
+				return nil, fmt.Errorf("HS256 tokens are no longer accepted")
+			}
+			return []byte(hmacSecret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodEd25519:
+			kid, _ := token.Header["kid"].(string)
+			ks.mu.RLock()
+			key, ok := ks.keys[kid]
+			ks.mu.RUnlock()
+			if !ok {
+				return nil, fmt.Errorf("unknown signing key %q", kid)
+			}
+			if key.method.Alg() != token.Method.Alg() {
+				return nil, fmt.Errorf("signing method %s does not match key %q", token.Method.Alg(), kid)
+			}
+			return key.private.Public(), nil
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	}
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key in the set
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func readKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: key}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: key}, nil
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", path)
+	}
+}
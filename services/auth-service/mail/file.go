@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSender is a development sink that appends emails to a file as JSON
+// lines, or writes them to the service log when no path is configured
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+// Send records the email instead of delivering it
+func (s *FileSender) Send(ctx context.Context, to, subject, body string) error {
+	if s.path == "" {
+		slog.InfoContext(ctx, "Email recorded", "to", to, "subject", subject, "body", body)
+		return nil
+	}
+
+	line, err := json.Marshal(map[string]string{
+		"to":      to,
+		"subject": subject,
+		"body":    body,
+		"sentAt":  time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open mail sink: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
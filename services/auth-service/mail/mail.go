@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"margwa/auth-service/config"
+)
+
+// Sender delivers a plain-text email
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// NewSender returns the mail sender selected by MAIL_PROVIDER
+func NewSender(cfg *config.Config) (Sender, error) {
+	switch cfg.MailProvider {
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.MailFrom == "" {
+			return nil, fmt.Errorf("smtp mail provider requires SMTP_HOST and MAIL_FROM")
+		}
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "log", "file":
+		return NewFileSender(cfg.MailSinkPath), nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider: %s", cfg.MailProvider)
+	}
+}
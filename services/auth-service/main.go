@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -9,46 +10,156 @@ import (
 	"syscall"
 	"time"
 
+	"margwa/auth-service/account"
 	"margwa/auth-service/config"
-	"margwa/auth-service/database"
 	"margwa/auth-service/handlers"
+	"margwa/auth-service/keys"
+	"margwa/auth-service/mail"
 	"margwa/auth-service/middleware"
+	"margwa/auth-service/sms"
+	"margwa/auth-service/storage"
+	"margwa/auth-service/totp"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/health"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/metrics"
+	"github.com/margwa/shared/go/migrate"
+	"github.com/margwa/shared/go/postgres"
+	"github.com/margwa/shared/go/redisclient"
+	"github.com/margwa/shared/go/server"
+	"github.com/margwa/shared/go/tracing"
 )
 
+// schemaVersion is the newest shared/go/migrate migration this service
+// depends on (sessions.persona, with pre-persona sessions aligned)
+const schemaVersion = 20
+
 func main() {
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	runMigrations := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load("../../.env"); err != nil {
-		log.Println("No .env file found, using system environment variables")
-	}
+	env.Load("../../.env")
 
 	// Initialize configuration
-	cfg := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if *printConfig {
+		env.Print(os.Stdout, cfg)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
+
+	// JSON logs with request IDs; the standard log package writes through it too
+	logging.Setup("auth-service", cfg.LogLevel)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "auth-service", tracing.Config{
+		Exporter:    cfg.TraceExporter,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
 
 	// Initialize database
-	db, err := database.NewPostgresDB(cfg.DatabaseURL)
+	db, err := postgres.Connect(context.Background(), postgres.Config{
+		URL:      cfg.DatabaseURL,
+		MaxConns: 10,
+		MinConns: 2,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
+	// --migrate applies pending migrations and exits; otherwise refuse to
+	// start against a schema older than this build needs
+	if *runMigrations {
+		if err := migrate.Run(context.Background(), db); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := migrate.Require(context.Background(), db, schemaVersion); err != nil {
+		log.Fatalf("Incompatible database schema: %v", err)
+	}
+
 	// Initialize Redis
-	redisClient := database.NewRedisClient(cfg.RedisURL)
+	redisClient, err := redisclient.Connect(context.Background(), cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
 	defer redisClient.Close()
 
+	// Initialize access-token denylist; revocations must outlive impersonation
+	// tokens as well as regular ones
+	tokenDenylist := auth.NewDenylist(redisClient, cfg.DenylistFailMode, max(cfg.JWTExpiresIn, cfg.ImpersonationTTL))
+
+	// Load asymmetric signing keys (HS256 is used while none are configured)
+	signingKeys, err := keys.Load(cfg.JWTSigningKeysDir, cfg.JWTActiveKID)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+
+	// Reload signing keys on SIGHUP so keys can be rotated without a restart
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			if err := signingKeys.Reload(); err != nil {
+				log.Printf("Failed to reload JWT signing keys: %v", err)
+				continue
+			}
+			log.Println("JWT signing keys reloaded")
+		}
+	}()
+
+	// Initialize SMS delivery
+	smsSender, err := sms.NewSender(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure SMS provider: %v", err)
+	}
+
+	// Initialize email delivery
+	mailSender, err := mail.NewSender(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure mail provider: %v", err)
+	}
+
+	// Encrypts TOTP secrets at rest
+	totpSealer, err := totp.NewSealer(cfg.MFAEncryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize TOTP encryption: %v", err)
+	}
+
 	// Set Gin mode
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Prometheus metrics
+	m := metrics.New("auth-service")
+	m.WatchPostgres(db)
+	m.WatchRedis(redisClient)
+
 	// Create router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
 
 	// Apply middleware
+	router.Use(tracing.Middleware("auth-service"))
+	router.Use(logging.Middleware())
+	router.Use(m.Middleware())
 	router.Use(middleware.CORSMiddleware())
-	router.Use(middleware.Logger())
+	router.Use(auth.AuditImpersonation(db, "auth-service"))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -58,51 +169,95 @@ func main() {
 			"service":   "auth-service",
 		})
 	})
+	router.GET("/metrics", metrics.Handler())
+
+	// Liveness and readiness probes
+	probes := health.New("auth-service")
+	probes.Add("postgres", 2*time.Second, health.Postgres(db))
+	probes.Add("redis", 2*time.Second, health.Redis(redisClient))
+	router.GET("/livez", probes.Live)
+	router.GET("/readyz", probes.Ready)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, redisClient, cfg)
+	authHandler := handlers.NewAuthHandler(db, redisClient, cfg, smsSender, mailSender, tokenDenylist, signingKeys, totpSealer)
+
+	hmacSecret := ""
+	if cfg.JWTAcceptHS256 {
+		hmacSecret = cfg.JWTSecret
+	}
+	requireAuth := auth.Middleware(signingKeys.Keyfunc(hmacSecret), tokenDenylist)
+	noImpersonation := auth.BlockImpersonation()
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	// Routes
-	auth := router.Group("/auth")
+	authRoutes := router.Group("/auth")
 	{
-		auth.POST("/register", authHandler.Register)
-		auth.POST("/send-otp", authHandler.SendOTP)
-		auth.POST("/verify-otp", authHandler.VerifyOTP)
-		auth.POST("/refresh-token", authHandler.RefreshToken)
-		auth.POST("/logout", middleware.AuthMiddleware(cfg.JWTSecret), authHandler.Logout)
-		auth.GET("/profile", middleware.AuthMiddleware(cfg.JWTSecret), authHandler.GetProfile)
-		auth.PUT("/profile", middleware.AuthMiddleware(cfg.JWTSecret), authHandler.UpdateProfile)
+		authRoutes.POST("/register", authHandler.Register)
+		authRoutes.POST("/send-otp", authHandler.SendOTP)
+		authRoutes.POST("/verify-otp", authHandler.VerifyOTP)
+		authRoutes.POST("/refresh-token", authHandler.RefreshToken)
+		authRoutes.POST("/switch-persona", requireAuth, noImpersonation, authHandler.SwitchPersona)
+		authRoutes.POST("/logout", requireAuth, authHandler.Logout)
+		authRoutes.GET("/profile", requireAuth, authHandler.GetProfile)
+		authRoutes.PUT("/profile", requireAuth, authHandler.UpdateProfile)
+		authRoutes.POST("/email/verify/start", requireAuth, noImpersonation, authHandler.StartEmailVerification)
+		authRoutes.POST("/email/verify/confirm", authHandler.ConfirmEmailVerification)
+		authRoutes.POST("/phone/change/start", requireAuth, noImpersonation, authHandler.StartPhoneChange)
+		authRoutes.POST("/phone/change/confirm", requireAuth, noImpersonation, authHandler.ConfirmPhoneChange)
+		authRoutes.GET("/sessions", requireAuth, authHandler.ListSessions)
+		authRoutes.GET("/security-events", requireAuth, noImpersonation, authHandler.SecurityEvents)
+		authRoutes.DELETE("/sessions/:id", requireAuth, noImpersonation, authHandler.RevokeSession)
+		authRoutes.POST("/logout-others", requireAuth, noImpersonation, authHandler.LogoutOthers)
+		authRoutes.POST("/account/export", requireAuth, noImpersonation, authHandler.ExportAccount)
+		authRoutes.DELETE("/account", requireAuth, noImpersonation, authHandler.DeleteAccount)
+		authRoutes.POST("/account/cancel-deletion", requireAuth, noImpersonation, authHandler.CancelAccountDeletion)
 	}
 
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// Second factor (TOTP authenticator apps)
+	mfa := router.Group("/auth/mfa")
+	mfa.Use(requireAuth, noImpersonation)
+	{
+		mfa.GET("", authHandler.MFAStatus)
+		mfa.POST("/totp/enroll", authHandler.EnrollTOTP)
+		mfa.POST("/totp/confirm", authHandler.ConfirmTOTP)
+		mfa.POST("/verify", authHandler.VerifyMFA)
+		mfa.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+		mfa.DELETE("/totp", authHandler.DisableTOTP)
 	}
 
-	// Start server in goroutine
-	go func() {
-		log.Printf("🚀 Auth Service running on port %s", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
-
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Admin routes
+	admin := router.Group("/auth/admin")
+	admin.Use(requireAuth, noImpersonation)
+	{
+		admin.POST("/impersonate/:userId", auth.RequirePermission("users:impersonate"), authHandler.Impersonate)
+		admin.GET("/roles", auth.RequirePermission("roles:manage"), authHandler.ListRoles)
+		admin.POST("/users/:id/roles", auth.RequirePermission("roles:manage"), authHandler.GrantRole)
+		admin.DELETE("/users/:id/roles/:role", auth.RequirePermission("roles:manage"), authHandler.RevokeRole)
+	}
 
-	log.Println("Shutting down server...")
+	// Start server; on SIGINT/SIGTERM readiness fails first, then in-flight
+	// requests finish before the listener closes
+	srv := server.New(router, server.Config{
+		Port:              cfg.Port,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		DrainDelay:        cfg.ShutdownDrainDelay,
+		ShutdownTimeout:   cfg.ShutdownTimeout,
+	})
+	srv.OnDrain(probes.Drain)
+	srv.OnShutdown("tracing", shutdownTracing)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// Erase accounts whose deletion grace period has passed
+	fileStorage := storage.NewClient(cfg.StorageServiceURL, cfg.StorageInternalToken)
+	srv.Go(account.NewPurger(db, tokenDenylist, fileStorage, cfg.AccountPurgeInterval).Run)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	log.Printf("🚀 Auth Service running on port %s", cfg.Port)
+	if err := srv.Run(); err != nil {
+		log.Fatalf("Server stopped: %v", err)
 	}
 
 	log.Println("Server exited")
@@ -7,46 +7,82 @@ import (
 )
 
 type User struct {
-	ID                 uuid.UUID  `json:"id"`
-	PhoneNumber        string     `json:"phoneNumber"`
-	PhoneCountryCode   string     `json:"phoneCountryCode"`
-	FullName           *string    `json:"fullName"`
-	Email              *string    `json:"email"`
-	ProfileImageURL    *string    `json:"profileImageUrl"`
-	DateOfBirth        *time.Time `json:"dob"`
-	Gender             *string    `json:"gender"`
-	IsProfileComplete  *bool      `json:"isProfileComplete"`
-	UserType           string     `json:"userType"`
-	IsVerified         bool       `json:"isVerified"`
-	IsActive           bool       `json:"isActive"`
-	LanguagePreference string     `json:"languagePreference"`
-	CreatedAt          time.Time  `json:"createdAt"`
-	UpdatedAt          time.Time  `json:"updatedAt"`
-	LastLoginAt        *time.Time `json:"lastLoginAt"`
+	ID                  uuid.UUID  `json:"id"`
+	PhoneNumber         string     `json:"phoneNumber"`
+	PhoneCountryCode    string     `json:"phoneCountryCode"`
+	FullName            *string    `json:"fullName"`
+	Email               *string    `json:"email"`
+	EmailVerifiedAt     *time.Time `json:"emailVerifiedAt"`
+	ProfileImageURL     *string    `json:"profileImageUrl"`
+	DateOfBirth         *time.Time `json:"dob"`
+	Gender              *string    `json:"gender"`
+	IsProfileComplete   *bool      `json:"isProfileComplete"`
+	UserType            string     `json:"userType"`
+	IsVerified          bool       `json:"isVerified"`
+	IsActive            bool       `json:"isActive"`
+	LanguagePreference  string     `json:"languagePreference"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+	LastLoginAt         *time.Time `json:"lastLoginAt"`
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty"`
+	Roles               []string   `json:"roles,omitempty"`
+	Permissions         []string   `json:"permissions,omitempty"`
 }
 
 type OTPVerification struct {
-	ID          uuid.UUID  `json:"id"`
-	UserID      *uuid.UUID `json:"userId"`
-	PhoneNumber string     `json:"phoneNumber"`
-	OTPCode     string     `json:"otpCode"`
-	ExpiresAt   time.Time  `json:"expiresAt"`
-	VerifiedAt  *time.Time `json:"verifiedAt"`
-	Attempts    int        `json:"attempts"`
-	CreatedAt   time.Time  `json:"createdAt"`
+	ID             uuid.UUID  `json:"id"`
+	UserID         *uuid.UUID `json:"userId"`
+	PhoneNumber    string     `json:"phoneNumber"`
+	OTPCode        string     `json:"-"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	VerifiedAt     *time.Time `json:"verifiedAt"`
+	Attempts       int        `json:"attempts"`
+	DeliveryStatus string     `json:"deliveryStatus"`
+	CreatedAt      time.Time  `json:"createdAt"`
 }
 
 type Session struct {
-	ID           uuid.UUID  `json:"id"`
-	UserID       uuid.UUID  `json:"userId"`
-	RefreshToken string     `json:"refreshToken"`
-	DeviceID     *string    `json:"deviceId"`
-	DeviceType   *string    `json:"deviceType"`
-	FCMToken     *string    `json:"fcmToken"`
-	IPAddress    *string    `json:"ipAddress"`
-	ExpiresAt    time.Time  `json:"expiresAt"`
-	CreatedAt    time.Time  `json:"createdAt"`
-	LastUsedAt   *time.Time `json:"lastUsedAt"`
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"userId"`
+	RefreshToken  string     `json:"refreshToken"`
+	DeviceID      *string    `json:"deviceId"`
+	DeviceType    *string    `json:"deviceType"`
+	FCMToken      *string    `json:"fcmToken"`
+	IPAddress     *string    `json:"ipAddress"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	LastUsedAt    *time.Time `json:"lastUsedAt"`
+	Persona       string     `json:"persona"`
+	RiskScore     *int       `json:"riskScore"`
+	RiskLevel     *string    `json:"riskLevel"`
+	RiskReasons   []string   `json:"riskReasons"`
+	StepUpMethod  *string    `json:"stepUpMethod"`
+	MFAVerifiedAt *time.Time `json:"mfaVerifiedAt"`
+}
+
+// SessionInfo is the client-facing view of a session; it never exposes the
+// refresh or FCM tokens
+type SessionInfo struct {
+	ID         uuid.UUID  `json:"id"`
+	DeviceID   *string    `json:"deviceId"`
+	DeviceType *string    `json:"deviceType"`
+	IPAddress  *string    `json:"ipAddress"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	Persona    string     `json:"persona"`
+	RiskLevel  *string    `json:"riskLevel"`
+	Current    bool       `json:"current"`
+}
+
+type SecurityEvent struct {
+	ID        uuid.UUID              `json:"id"`
+	EventType string                 `json:"eventType"`
+	IPAddress *string                `json:"ipAddress"`
+	DeviceID  *string                `json:"deviceId"`
+	UserAgent *string                `json:"userAgent"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
 }
 
 type RegisterRequest struct {
@@ -56,17 +92,75 @@ type RegisterRequest struct {
 }
 
 type SendOTPRequest struct {
-	PhoneNumber      string `json:"phoneNumber" binding:"required"`
-	PhoneCountryCode string `json:"phoneCountryCode" binding:"required"`
-}
-
-type VerifyOTPRequest struct {
 	PhoneNumber      string  `json:"phoneNumber" binding:"required"`
 	PhoneCountryCode string  `json:"phoneCountryCode" binding:"required"`
-	OTPCode          string  `json:"otpCode" binding:"required"`
 	DeviceID         *string `json:"deviceId"`
-	DeviceType       *string `json:"deviceType"`
-	FCMToken         *string `json:"fcmToken"`
+	Channel          *string `json:"channel" binding:"omitempty,oneof=sms email"`
+}
+
+type VerifyOTPRequest struct {
+	PhoneNumber      string   `json:"phoneNumber" binding:"required"`
+	PhoneCountryCode string   `json:"phoneCountryCode" binding:"required"`
+	OTPCode          string   `json:"otpCode" binding:"required"`
+	DeviceID         *string  `json:"deviceId"`
+	DeviceType       *string  `json:"deviceType"`
+	FCMToken         *string  `json:"fcmToken"`
+	Latitude         *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude        *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	StepUpCode       *string  `json:"stepUpCode"`
+	Persona          *string  `json:"persona" binding:"omitempty,oneof=client driver"`
+}
+
+type StartPhoneChangeRequest struct {
+	NewPhoneNumber      string `json:"newPhoneNumber" binding:"required"`
+	NewPhoneCountryCode string `json:"newPhoneCountryCode" binding:"required"`
+}
+
+type ConfirmPhoneChangeRequest struct {
+	NewPhoneNumber      string `json:"newPhoneNumber" binding:"required"`
+	NewPhoneCountryCode string `json:"newPhoneCountryCode" binding:"required"`
+	OldOTPCode          string `json:"oldOtpCode" binding:"required"`
+	NewOTPCode          string `json:"newOtpCode" binding:"required"`
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RoleInfo struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type GrantRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// MFACodeRequest carries a 6-digit authenticator code or a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type MFAStatus struct {
+	TOTPEnabled            bool       `json:"totpEnabled"`
+	EnrolledAt             *time.Time `json:"enrolledAt"`
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"`
+	SessionVerified        bool       `json:"sessionVerified"`
+}
+
+type SwitchPersonaRequest struct {
+	Persona string `json:"persona" binding:"required,oneof=client driver"`
+}
+
+type ImpersonateRequest struct {
+	Reason  string  `json:"reason" binding:"required,min=10"`
+	Persona *string `json:"persona" binding:"omitempty,oneof=client driver"`
 }
 
 type RefreshTokenRequest struct {
@@ -75,7 +169,7 @@ type RefreshTokenRequest struct {
 
 type UpdateProfileRequest struct {
 	FullName           *string    `json:"fullName"`
-	Email              *string    `json:"email"`
+	Email              *string    `json:"email" binding:"omitempty,email"`
 	ProfileImageURL    *string    `json:"profileImageUrl"`
 	DateOfBirth        *time.Time `json:"dob"`
 	Gender             *string    `json:"gender"`
@@ -83,24 +177,11 @@ type UpdateProfileRequest struct {
 	LanguagePreference *string    `json:"languagePreference"`
 }
 
-type AuthResponse struct {
-	Success   bool        `json:"success"`
-	Data      interface{} `json:"data,omitempty"`
-	Message   string      `json:"message,omitempty"`
-	Error     *ErrorData  `json:"error,omitempty"`
-	Timestamp string      `json:"timestamp"`
-}
-
-type ErrorData struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
-}
-
 type TokenPair struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
 	ExpiresIn    string `json:"expiresIn"`
+	Persona      string `json:"persona,omitempty"`
 }
 
 type UserWithTokens struct {
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/margwa/shared/go/tracing"
+)
+
+// Notification is the payload accepted by notification-service's
+// POST /notifications
+type Notification struct {
+	UserID           string            `json:"userId"`
+	Title            string            `json:"title"`
+	Body             string            `json:"body"`
+	NotificationType string            `json:"notificationType"`
+	Data             map[string]string `json:"data,omitempty"`
+	DeviceToken      string            `json:"deviceToken,omitempty"`
+}
+
+// Client creates notifications through notification-service, which stores
+// them, pushes to the device token over FCM and relays to open websockets
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport()},
+	}
+}
+
+// Send posts a notification for delivery
+func (c *Client) Send(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/notifications", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notification request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification-service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
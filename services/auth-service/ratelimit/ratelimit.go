@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Rule caps requests for one key within a sliding window
+type Rule struct {
+	Key    string
+	Limit  int
+	Window time.Duration
+}
+
+// slidingWindow checks every rule and records the request only if all of
+// them pass, so a rejected request does not use up quota. Each key is a
+// sorted set of request timestamps in milliseconds. Returns 0 when allowed,
+// otherwise the milliseconds until the most constrained window frees a slot.
+var slidingWindow = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local member = ARGV[2]
+local wait = 0
+for i, key in ipairs(KEYS) do
+	local limit = tonumber(ARGV[1 + i * 2])
+	local window = tonumber(ARGV[2 + i * 2])
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+	if redis.call('ZCARD', key) >= limit then
+		local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+		local retry = tonumber(oldest[2]) + window - now
+		if retry > wait then wait = retry end
+	end
+end
+if wait > 0 then return wait end
+for i, key in ipairs(KEYS) do
+	redis.call('ZADD', key, now, member)
+	redis.call('PEXPIRE', key, tonumber(ARGV[2 + i * 2]))
+end
+return 0
+`)
+
+// CooldownPolicy turns repeated failures into exponentially growing lockouts:
+// after Threshold failures within Window, each further failure locks the
+// subject out for Base * 2^(failures-Threshold), capped at Max.
+type CooldownPolicy struct {
+	Threshold int
+	Window    time.Duration
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Limiter applies Redis-backed rate limits and failure cooldowns
+type Limiter struct {
+	client   *redis.Client
+	cooldown CooldownPolicy
+}
+
+func New(client *redis.Client, cooldown CooldownPolicy) *Limiter {
+	return &Limiter{client: client, cooldown: cooldown}
+}
+
+// Allow records a request against every rule. Rules with an empty key or a
+// non-positive limit are skipped. When any window is full it returns false
+// and how long to wait.
+func (l *Limiter) Allow(ctx context.Context, rules ...Rule) (bool, time.Duration, error) {
+	keys := make([]string, 0, len(rules))
+	args := []interface{}{time.Now().UnixMilli(), uuid.NewString()}
+	for _, rule := range rules {
+		if rule.Key == "" || rule.Limit <= 0 {
+			continue
+		}
+		keys = append(keys, "auth:ratelimit:"+rule.Key)
+		args = append(args, rule.Limit, rule.Window.Milliseconds())
+	}
+	if len(keys) == 0 {
+		return true, 0, nil
+	}
+
+	wait, err := slidingWindow.Run(ctx, l.client, keys, args...).Int64()
+	if err != nil {
+		return true, 0, fmt.Errorf("rate limit check failed: %w", err)
+	}
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+// Cooldown returns the longest remaining lockout across subjects, or 0
+func (l *Limiter) Cooldown(ctx context.Context, subjects ...string) (time.Duration, error) {
+	var longest time.Duration
+	for _, subject := range subjects {
+		if subject == "" {
+			continue
+		}
+		ttl, err := l.client.PTTL(ctx, "auth:cooldown:"+subject).Result()
+		if err != nil {
+			return 0, fmt.Errorf("cooldown check failed: %w", err)
+		}
+		if ttl > longest {
+			longest = ttl
+		}
+	}
+	return longest, nil
+}
+
+// RecordFailure counts a failure for each subject and starts a cooldown once
+// the threshold is reached
+func (l *Limiter) RecordFailure(ctx context.Context, subjects ...string) error {
+	for _, subject := range subjects {
+		if subject == "" {
+			continue
+		}
+
+		failKey := "auth:failures:" + subject
+		failures, err := l.client.Incr(ctx, failKey).Result()
+		if err != nil {
+			return err
+		}
+		if failures == 1 {
+			l.client.PExpire(ctx, failKey, l.cooldown.Window)
+		}
+
+		if l.cooldown.Threshold <= 0 || failures < int64(l.cooldown.Threshold) {
+			continue
+		}
+
+		cooldown := l.cooldown.Base
+		for i := int64(l.cooldown.Threshold); i < failures && cooldown < l.cooldown.Max; i++ {
+			cooldown *= 2
+		}
+		if cooldown > l.cooldown.Max {
+			cooldown = l.cooldown.Max
+		}
+		if err := l.client.Set(ctx, "auth:cooldown:"+subject, 1, cooldown).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetFailures clears the failure count for subjects, e.g. after a success
+func (l *Limiter) ResetFailures(ctx context.Context, subjects ...string) error {
+	keys := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		if subject != "" {
+			keys = append(keys, "auth:failures:"+subject)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return l.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,231 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Risk levels stored on sessions.risk_level
+const (
+	LevelLow    = "low"
+	LevelMedium = "medium"
+	LevelHigh   = "high"
+)
+
+// Signals that contribute to a login's risk score
+const (
+	SignalNewDevice        = "new_device"
+	SignalNewIPRange       = "new_ip_range"
+	SignalImpossibleTravel = "impossible_travel"
+	SignalOTPFailureBurst  = "otp_failure_burst"
+)
+
+var weights = map[string]int{
+	SignalNewDevice:        30,
+	SignalNewIPRange:       20,
+	SignalImpossibleTravel: 60,
+	SignalOTPFailureBurst:  25,
+}
+
+// Policy holds the tunable parts of the assessment
+type Policy struct {
+	MediumThreshold int
+	HighThreshold   int
+	// FailureBurst OTP failures within FailureWindow count as a burst
+	FailureBurst  int
+	FailureWindow time.Duration
+	// MaxTravelSpeed in km/h; anything faster between the driver's last
+	// known location and the login location is impossible travel
+	MaxTravelSpeed float64
+}
+
+// Login describes the sign-in being assessed
+type Login struct {
+	UserID    string
+	DeviceID  string
+	IPAddress string
+	Latitude  *float64
+	Longitude *float64
+}
+
+// Assessment is the outcome stored with the session
+type Assessment struct {
+	Score   int      `json:"score"`
+	Level   string   `json:"level"`
+	Reasons []string `json:"reasons"`
+}
+
+// AtLeast reports whether the assessment is at or above level
+func (a *Assessment) AtLeast(level string) bool {
+	return rank(a.Level) >= rank(level)
+}
+
+// Assessor scores logins against the user's previous logins and recent
+// OTP failures
+type Assessor struct {
+	db     *pgxpool.Pool
+	policy Policy
+}
+
+func NewAssessor(db *pgxpool.Pool, policy Policy) *Assessor {
+	return &Assessor{db: db, policy: policy}
+}
+
+// Assess scores a login. A user's first login is never risky since there is
+// nothing to compare it with.
+func (a *Assessor) Assess(ctx context.Context, login Login) (*Assessment, error) {
+	var reasons []string
+
+	devices, ipRanges, err := a.history(ctx, login.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("load login history: %w", err)
+	}
+
+	if len(devices) > 0 || len(ipRanges) > 0 {
+		if login.DeviceID == "" || !devices[login.DeviceID] {
+			reasons = append(reasons, SignalNewDevice)
+		}
+		if prefix := ipRange(login.IPAddress); prefix == "" || !ipRanges[prefix] {
+			reasons = append(reasons, SignalNewIPRange)
+		}
+	}
+
+	impossible, err := a.impossibleTravel(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("check travel: %w", err)
+	}
+	if impossible {
+		reasons = append(reasons, SignalImpossibleTravel)
+	}
+
+	var failures int
+	err = a.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM auth_events
+		 WHERE user_id = $1 AND event_type = 'otp_failed' AND created_at > $2`,
+		login.UserID, time.Now().Add(-a.policy.FailureWindow),
+	).Scan(&failures)
+	if err != nil {
+		return nil, fmt.Errorf("count otp failures: %w", err)
+	}
+	if failures >= a.policy.FailureBurst {
+		reasons = append(reasons, SignalOTPFailureBurst)
+	}
+
+	assessment := &Assessment{Level: LevelLow, Reasons: []string{}}
+	for _, reason := range reasons {
+		assessment.Score += weights[reason]
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+	switch {
+	case assessment.Score >= a.policy.HighThreshold:
+		assessment.Level = LevelHigh
+	case assessment.Score >= a.policy.MediumThreshold:
+		assessment.Level = LevelMedium
+	}
+
+	return assessment, nil
+}
+
+// history returns the device IDs and IP ranges the user has logged in from.
+// Sessions cover logins made before auth_events existed.
+func (a *Assessor) history(ctx context.Context, userID string) (map[string]bool, map[string]bool, error) {
+	rows, err := a.db.Query(ctx,
+		`SELECT device_id, ip_address FROM auth_events WHERE user_id = $1 AND event_type = 'login'
+		 UNION
+		 SELECT device_id, ip_address FROM sessions WHERE user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	devices := map[string]bool{}
+	ipRanges := map[string]bool{}
+	for rows.Next() {
+		var deviceID, ip *string
+		if err := rows.Scan(&deviceID, &ip); err != nil {
+			return nil, nil, err
+		}
+		if deviceID != nil && *deviceID != "" {
+			devices[*deviceID] = true
+		}
+		if ip != nil {
+			if prefix := ipRange(*ip); prefix != "" {
+				ipRanges[prefix] = true
+			}
+		}
+	}
+	return devices, ipRanges, rows.Err()
+}
+
+// impossibleTravel compares the login location with the driver's last
+// reported position. Riders have no tracked position and are never flagged.
+func (a *Assessor) impossibleTravel(ctx context.Context, login Login) (bool, error) {
+	if login.Latitude == nil || login.Longitude == nil {
+		return false, nil
+	}
+
+	var lat, lng float64
+	var updatedAt time.Time
+	err := a.db.QueryRow(ctx,
+		`SELECT current_latitude::float8, current_longitude::float8, last_location_update
+		 FROM driver_profiles
+		 WHERE user_id = $1 AND current_latitude IS NOT NULL
+		   AND current_longitude IS NOT NULL AND last_location_update IS NOT NULL`,
+		login.UserID,
+	).Scan(&lat, &lng, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Allow at least a minute so GPS jitter right after an update is not
+	// mistaken for supersonic travel
+	hours := math.Max(time.Since(updatedAt).Hours(), 1.0/60)
+	distance := haversineKm(lat, lng, *login.Latitude, *login.Longitude)
+	return distance/hours > a.policy.MaxTravelSpeed, nil
+}
+
+// ipRange groups addresses by /24 for IPv4 and /48 for IPv6, roughly one
+// network or provider allocation
+func ipRange(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+func rank(level string) int {
+	switch level {
+	case LevelHigh:
+		return 2
+	case LevelMedium:
+		return 1
+	}
+	return 0
+}
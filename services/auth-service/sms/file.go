@@ -0,0 +1,52 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSender is a development sink that appends messages to a file as JSON
+// lines. Without a path it writes them to the service log instead, where the
+// log redactor masks the number and the code; read codes from the file.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+// Send records the message instead of delivering it
+func (s *FileSender) Send(ctx context.Context, to, body string) error {
+	if s.path == "" {
+		slog.InfoContext(ctx, "SMS recorded; set SMS_SINK_PATH to read the code", "to", to, "body", body)
+		return nil
+	}
+
+	line, err := json.Marshal(map[string]string{
+		"to":     to,
+		"body":   body,
+		"sentAt": time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open SMS sink: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,28 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"margwa/auth-service/config"
+)
+
+// SMSSender delivers a text message to a phone number in E.164 format
+type SMSSender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewSender returns the SMS sender selected by SMS_PROVIDER
+func NewSender(cfg *config.Config) (SMSSender, error) {
+	switch cfg.SMSProvider {
+	case "twilio":
+		if cfg.TwilioSID == "" || cfg.TwilioAuthToken == "" || cfg.TwilioPhoneNumber == "" {
+			return nil, fmt.Errorf("twilio SMS provider requires TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_PHONE_NUMBER")
+		}
+		return NewTwilioSender(cfg.TwilioAPIURL, cfg.TwilioSID, cfg.TwilioAuthToken, cfg.TwilioPhoneNumber), nil
+	case "log", "file":
+		return NewFileSender(cfg.SMSSinkPath), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider: %s", cfg.SMSProvider)
+	}
+}
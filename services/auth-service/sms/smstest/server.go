@@ -0,0 +1,116 @@
+// Package smstest provides an in-process stand-in for the Twilio Messages API
+// so the SMS flow can be exercised without a real account.
+package smstest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Message is a message captured by the mock server
+type Message struct {
+	AccountSID string
+	To         string
+	From       string
+	Body       string
+}
+
+// Server mimics POST /2010-04-01/Accounts/{sid}/Messages.json
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	messages []Message
+	failWith int
+}
+
+// NewServer starts a mock Twilio API; point TwilioSender at Server.URL
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// FailWith makes subsequent requests fail with the given HTTP status, or
+// succeed again when status is 0
+func (s *Server) FailWith(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failWith = status
+}
+
+// Messages returns a copy of all messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// LastMessageTo returns the most recent message sent to a number
+func (s *Server) LastMessageTo(to string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].To == to {
+			return s.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if r.Method != http.MethodPost || len(parts) != 4 || parts[0] != "2010-04-01" ||
+		parts[1] != "Accounts" || parts[3] != "Messages.json" {
+		http.NotFound(w, r)
+		return
+	}
+
+	sid, _, ok := r.BasicAuth()
+	if !ok || sid != parts[2] {
+		writeError(w, http.StatusUnauthorized, 20003, "Authenticate")
+		return
+	}
+
+	s.mu.Lock()
+	failWith := s.failWith
+	s.mu.Unlock()
+	if failWith != 0 {
+		writeError(w, failWith, 30008, "Unknown error")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, 21602, "Invalid form body")
+		return
+	}
+
+	msg := Message{
+		AccountSID: sid,
+		To:         r.PostForm.Get("To"),
+		From:       r.PostForm.Get("From"),
+		Body:       r.PostForm.Get("Body"),
+	}
+	if msg.To == "" {
+		writeError(w, http.StatusBadRequest, 21604, "A 'To' phone number is required.")
+		return
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	count := len(s.messages)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"sid":"SM%032d","status":"queued","to":%q,"from":%q}`, count, msg.To, msg.From)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"code":%d,"message":%q,"status":%d}`, code, message, status)
+}
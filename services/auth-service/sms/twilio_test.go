@@ -0,0 +1,66 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"margwa/auth-service/sms/smstest"
+)
+
+func TestTwilioSenderSend(t *testing.T) {
+	server := smstest.NewServer()
+	defer server.Close()
+
+	sender := NewTwilioSender(server.URL, "AC123", "secret", "+15550001111")
+	if err := sender.Send(context.Background(), "+919876543210", "Your code is 123456"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	msg, ok := server.LastMessageTo("+919876543210")
+	if !ok {
+		t.Fatal("no message reached the mock Twilio API")
+	}
+	want := smstest.Message{AccountSID: "AC123", To: "+919876543210", From: "+15550001111", Body: "Your code is 123456"}
+	if msg != want {
+		t.Errorf("message = %+v, want %+v", msg, want)
+	}
+}
+
+func TestTwilioSenderSendErrorBody(t *testing.T) {
+	server := smstest.NewServer()
+	defer server.Close()
+	server.FailWith(http.StatusBadRequest)
+
+	sender := NewTwilioSender(server.URL, "AC123", "secret", "+15550001111")
+	err := sender.Send(context.Background(), "+919876543210", "Your code is 123456")
+	if err == nil {
+		t.Fatal("Send succeeded, want the Twilio error")
+	}
+	if want := "twilio error 30008: Unknown error"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if n := len(server.Messages()); n != 0 {
+		t.Errorf("%d messages recorded for a failed request", n)
+	}
+}
+
+func TestTwilioSenderSendNon2xx(t *testing.T) {
+	// A proxy or outage page rather than a Twilio JSON error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html>Service Unavailable</html>"))
+	}))
+	defer server.Close()
+
+	sender := NewTwilioSender(server.URL, "AC123", "secret", "+15550001111")
+	err := sender.Send(context.Background(), "+919876543210", "Your code is 123456")
+	if err == nil {
+		t.Fatal("Send succeeded against a 503")
+	}
+	if want := "twilio returned status 503"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/margwa/shared/go/tracing"
+)
+
+// Client removes stored objects through storage-service's internal
+// POST /api/v1/storage/files/delete
+type Client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second, Transport: tracing.Transport()},
+	}
+}
+
+// DeleteObjects removes the objects behind urls and returns the error message
+// for each URL storage-service could not delete. URLs outside its buckets
+// count as deleted.
+func (c *Client) DeleteObjects(ctx context.Context, urls []string) (map[string]string, error) {
+	payload, err := json.Marshal(map[string][]string{"urls": urls})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/storage/files/delete", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("storage-service returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Failed []struct {
+				URL   string `json:"url"`
+				Error string `json:"error"`
+			} `json:"failed"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid storage-service response: %w", err)
+	}
+
+	failed := make(map[string]string, len(body.Data.Failed))
+	for _, f := range body.Data.Failed {
+		failed[f.URL] = f.Error
+	}
+	return failed, nil
+}
@@ -1,24 +1,23 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math/big"
+	"regexp"
+	"strings"
 	"time"
 
 	"margwa/auth-service/models"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/margwa/shared/go/auth"
 )
 
-type JWTClaims struct {
-	UserID      string `json:"userId"`
-	UserType    string `json:"userType"`
-	PhoneNumber string `json:"phoneNumber"`
-	jwt.RegisteredClaims
-}
-
 // GenerateOTP creates a random OTP of specified length
 func GenerateOTP(length int) (string, error) {
 	const digits = "0123456789"
@@ -38,12 +37,44 @@ func GenerateOTP(length int) (string, error) {
 	return string(otp), nil
 }
 
-// GenerateJWT creates a new JWT token
-func GenerateJWT(user *models.User, secret string, expiresIn time.Duration) (string, error) {
-	claims := JWTClaims{
+// otpHashPrefix marks otp_code values stored as digests. Rows without it
+// predate hashing and are treated as expired.
+const otpHashPrefix = "hmac-sha256:"
+
+// HashOTP derives the digest stored for an OTP. The OTP row ID is mixed in
+// so identical codes never share a digest.
+func HashOTP(secret, otpID, code string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(otpID))
+	mac.Write([]byte{':'})
+	mac.Write([]byte(code))
+	return otpHashPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsHashedOTP reports whether a stored otp_code is a digest
+func IsHashedOTP(stored string) bool {
+	return strings.HasPrefix(stored, otpHashPrefix)
+}
+
+// VerifyOTPHash compares a submitted code against the stored digest in constant time
+func VerifyOTPHash(secret, otpID, code, stored string) bool {
+	if !IsHashedOTP(stored) {
+		return false
+	}
+	expected := HashOTP(secret, otpID, code)
+	return hmac.Equal([]byte(expected), []byte(stored))
+}
+
+// NewClaims builds the claims shared by access and refresh tokens. sessionID
+// ties the token to a row in sessions and may be empty.
+func NewClaims(user *models.User, sessionID string, expiresIn time.Duration) auth.Claims {
+	return auth.Claims{
 		UserID:      user.ID.String(),
 		UserType:    user.UserType,
 		PhoneNumber: user.PhoneNumber,
+		SessionID:   sessionID,
+		Roles:       user.Roles,
+		Permissions: user.Permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -51,59 +82,117 @@ func GenerateJWT(user *models.User, secret string, expiresIn time.Duration) (str
 			ID:        uuid.New().String(),
 		},
 	}
+}
+
+// GenerateJWT creates a new HS256 JWT token
+func GenerateJWT(user *models.User, sessionID string, secret string, expiresIn time.Duration) (string, error) {
+	return SignJWT(NewClaims(user, sessionID, expiresIn), secret)
+}
 
+// SignJWT signs prepared claims with HS256
+func SignJWT(claims auth.Claims, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
 
-// ValidateJWT verifies and parses a JWT token
-func ValidateJWT(tokenString string, secret string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+// ValidateJWT verifies and parses an HS256 JWT token
+func ValidateJWT(tokenString string, secret string) (*auth.Claims, error) {
+	return auth.ParseToken(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(secret), nil
 	})
+}
+
+// emailTokenAudience keeps email verification tokens from being accepted as
+// anything else signed with the same secret
+const emailTokenAudience = "email-verification"
+
+// EmailTokenClaims are carried by the signed token in an email verification link
+type EmailTokenClaims struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GenerateEmailToken signs a token proving that the holder received mail at email
+func GenerateEmailToken(userID, email, secret string, expiresIn time.Duration) (string, error) {
+	claims := EmailTokenClaims{
+		UserID: userID,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{emailTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateEmailToken verifies and parses an email verification token
+func ValidateEmailToken(tokenString, secret string) (*EmailTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &EmailTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience(emailTokenAudience))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*EmailTokenClaims); ok && token.Valid {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
-// ParseDuration parses duration string like "15m", "24h"
-func ParseDuration(s string) time.Duration {
-	duration, err := time.ParseDuration(s)
-	if err != nil {
-		return 15 * time.Minute
+var (
+	e164Pattern        = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	countryCodePattern = regexp.MustCompile(`^\+[1-9][0-9]{0,2}$`)
+	phoneSeparators    = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+)
+
+// NormalizePhoneNumber returns the E.164 form of a phone number along with its
+// country code. The number may be given in national form (a leading trunk 0
+// is dropped) or already include the country code.
+func NormalizePhoneNumber(countryCode, phoneNumber string) (string, string, error) {
+	countryCode = phoneSeparators.Replace(strings.TrimSpace(countryCode))
+	if !strings.HasPrefix(countryCode, "+") {
+		countryCode = "+" + countryCode
+	}
+	if !countryCodePattern.MatchString(countryCode) {
+		return "", "", fmt.Errorf("invalid country code %q", countryCode)
 	}
-	return duration
-}
 
-// SuccessResponse creates a success API response
-func SuccessResponse(data interface{}, message string) models.AuthResponse {
-	return models.AuthResponse{
-		Success:   true,
-		Data:      data,
-		Message:   message,
-		Timestamp: time.Now().Format(time.RFC3339),
+	number := phoneSeparators.Replace(strings.TrimSpace(phoneNumber))
+	switch {
+	case strings.HasPrefix(number, "+"):
+		if !strings.HasPrefix(number, countryCode) {
+			return "", "", fmt.Errorf("phone number does not match country code %s", countryCode)
+		}
+	case strings.HasPrefix(number, "00"):
+		number = "+" + number[2:]
+		if !strings.HasPrefix(number, countryCode) {
+			return "", "", fmt.Errorf("phone number does not match country code %s", countryCode)
+		}
+	default:
+		number = countryCode + strings.TrimLeft(number, "0")
 	}
+
+	if !e164Pattern.MatchString(number) {
+		return "", "", fmt.Errorf("invalid phone number")
+	}
+	return number, countryCode, nil
 }
 
-// ErrorResponse creates an error API response
-func ErrorResponse(code, message string, details interface{}) models.AuthResponse {
-	return models.AuthResponse{
-		Success: false,
-		Error: &models.ErrorData{
-			Code:    code,
-			Message: message,
-			Details: details,
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
+// MaskEmail hides most of the local part of an address, e.g. j***@example.com
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
 	}
+	return email[:1] + "***" + email[at:]
 }
@@ -1,45 +1,69 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/margwa/shared/go/env"
 )
 
 type Config struct {
-	Port        string
-	DatabaseURL string
-	JWTSecret   string
-	Environment string
+	Port             string `env:"PORT" default:"3003"`
+	DatabaseURL      string `env:"DATABASE_URL" required:"true" secret:"true"`
+	RedisURL         string `env:"REDIS_URL" default:"redis://localhost:6379"`
+	JWTSecret        string `env:"JWT_SECRET" secret:"true"`
+	Environment      string `env:"ENVIRONMENT" default:"development"`
+	DenylistFailMode string `env:"TOKEN_DENYLIST_FAIL_MODE" default:"open" oneof:"open,closed"`
+	JWKSURL          string `env:"JWKS_URL"`
+	JWTAcceptHS256   bool   `env:"JWT_ACCEPT_HS256" default:"true"`
+	StorageURL       string `env:"STORAGE_SERVICE_URL" default:"http://localhost:3010"`
+
+	LogLevel string `env:"LOG_LEVEL" default:"info" oneof:"debug,info,warn,error"`
+
+	// How long /readyz reports draining before the server stops accepting
+	// connections, so load balancers can take the instance out first
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	// Bounds waiting for in-flight requests, then background workers
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s"`
+
+	// HTTP server timeouts. Document uploads stream multipart bodies to
+	// storage-service, so reads and writes get more time than elsewhere
+	HTTPReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	HTTPReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" default:"2m"`
+	HTTPWriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"2m"`
+	HTTPIdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"1m"`
+
+	// Tracing: otlp sends spans to OTEL_EXPORTER_OTLP_ENDPOINT, stdout prints them
+	TraceExporter    string  `env:"OTEL_TRACES_EXPORTER" default:"none" oneof:"otlp,stdout,none"`
+	TraceSampleRatio float64 `env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
 }
 
+// LoadConfig reads the configuration from the environment and validates it.
+// The config is returned even when invalid so --print-config can show it.
 func LoadConfig() (*Config, error) {
-	// Load .env file if it exists
-	godotenv.Load()
-
-	cfg := &Config{
-		Port:        getEnv("PORT", "3003"),
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		JWTSecret:   os.Getenv("JWT_SECRET"),
-		Environment: getEnv("ENVIRONMENT", "development"),
-	}
+	cfg := &Config{}
+	err := env.Parse(cfg)
+	return cfg, errors.Join(err, cfg.validate())
+}
 
-	// Validate required fields
-	if cfg.DatabaseURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL is not defined")
+func (c *Config) validate() error {
+	var errs []error
+	if c.Environment == "production" {
+		if names := env.DefaultSecrets(c); len(names) > 0 {
+			errs = append(errs, fmt.Errorf("%s must be set in production", strings.Join(names, ", ")))
+		}
 	}
-	if cfg.JWTSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is not defined")
+	if c.JWTAcceptHS256 && c.JWTSecret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required when JWT_ACCEPT_HS256 is true"))
 	}
-
-	return cfg, nil
-}
-
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	if !c.JWTAcceptHS256 && c.JWKSURL == "" {
+		errs = append(errs, errors.New("JWKS_URL is required when JWT_ACCEPT_HS256 is false"))
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1, got %g", c.TraceSampleRatio))
 	}
-	return value
+	return errors.Join(errs...)
 }
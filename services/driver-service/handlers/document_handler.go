@@ -6,18 +6,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"os"
 
 	"margwa/driver-service/models"
-	"margwa/driver-service/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
 )
 
 type DocumentHandler struct {
@@ -55,18 +56,18 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 
 	// Get driver ID
 	var driverID uuid.UUID
-	err := h.db.QueryRow(context.Background(),
+	err := h.db.QueryRow(tracing.Context(c),
 		`SELECT id FROM driver_profiles WHERE user_id = $1`,
 		userID,
 	).Scan(&driverID)
 
 	if err != nil {
-		log.Printf("Error getting driver ID: %v", err)
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("NOT_FOUND", "Driver profile not found", nil))
+		logging.Errorf(tracing.Context(c), "Error getting driver ID: %v", err)
+		c.JSON(http.StatusNotFound, response.Error("NOT_FOUND", "Driver profile not found", nil))
 		return
 	}
 
-	rows, err := h.db.Query(context.Background(),
+	rows, err := h.db.Query(tracing.Context(c),
 		`SELECT id, driver_id, document_type, document_url, verification_status, 
 		 verified_at, expires_at, created_at
 		 FROM driver_documents WHERE driver_id = $1 ORDER BY created_at DESC`,
@@ -74,8 +75,8 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error getting documents: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to get documents", nil))
+		logging.Errorf(tracing.Context(c), "Error getting documents: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to get documents", nil))
 		return
 	}
 	defer rows.Close()
@@ -87,13 +88,13 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 			&doc.VerificationStatus, &doc.VerifiedAt, &doc.ExpiresAt, &doc.CreatedAt)
 
 		if err != nil {
-			log.Printf("Error scanning document: %v", err)
+			logging.Errorf(tracing.Context(c), "Error scanning document: %v", err)
 			continue
 		}
 		documents = append(documents, doc)
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(documents, "Documents retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(documents, "Documents retrieved successfully"))
 }
 
 // UploadDocument uploads a document via multipart upload
@@ -103,15 +104,15 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	// Get driver ID (with auto-create)
 	driverID, err := getOrCreateDriverID(c.Request.Context(), h.db, userID)
 	if err != nil {
-		log.Printf("Error getting/creating driver ID: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to process driver profile", nil))
+		logging.Errorf(tracing.Context(c), "Error getting/creating driver ID: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to process driver profile", nil))
 		return
 	}
 
 	// Parse multipart form
 	err = c.Request.ParseMultipartForm(10 << 20) // 10MB max
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("PARSE_ERROR", "Failed to parse form data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("PARSE_ERROR", "Failed to parse form data", err.Error()))
 		return
 	}
 
@@ -120,28 +121,28 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	expiresAt := c.PostForm("expiresAt")
 
 	if documentType == "" {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Document type is required", nil))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Document type is required", nil))
 		return
 	}
 
 	// Get uploaded file
 	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "File is required", nil))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "File is required", nil))
 		return
 	}
 
 	// Upload to storage service
-	documentURL, err := uploadDocumentToStorage(file, documentType, driverID.String())
+	documentURL, err := uploadDocumentToStorage(tracing.Context(c), file, documentType, driverID.String())
 	if err != nil {
-		log.Printf("Error uploading document to storage: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("UPLOAD_ERROR", "Failed to upload document", err.Error()))
+		logging.Errorf(tracing.Context(c), "Error uploading document to storage: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("UPLOAD_ERROR", "Failed to upload document", err.Error()))
 		return
 	}
 
 	// Check if document type already exists
 	var existingID uuid.UUID
-	err = h.db.QueryRow(context.Background(),
+	err = h.db.QueryRow(tracing.Context(c),
 		`SELECT id FROM driver_documents WHERE driver_id = $1 AND document_type = $2`,
 		driverID, documentType,
 	).Scan(&existingID)
@@ -154,7 +155,7 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 
 	if err == nil {
 		// Update existing document
-		_, err = h.db.Exec(context.Background(),
+		_, err = h.db.Exec(tracing.Context(c),
 			`UPDATE driver_documents SET 
 			 document_url = $1,
 			 expires_at = $2,
@@ -165,34 +166,34 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		)
 
 		if err != nil {
-			log.Printf("Error updating document: %v", err)
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update document", nil))
+			logging.Errorf(tracing.Context(c), "Error updating document: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update document", nil))
 			return
 		}
 
-		c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"id": existingID}, "Document updated successfully"))
+		c.JSON(http.StatusOK, response.Success(gin.H{"id": existingID}, "Document updated successfully"))
 		return
 	}
 
 	// Create new document
 	documentID := uuid.New()
-	_, err = h.db.Exec(context.Background(),
+	_, err = h.db.Exec(tracing.Context(c),
 		`INSERT INTO driver_documents (id, driver_id, document_type, document_url, expires_at, verification_status)
 		 VALUES ($1, $2, $3, $4, $5, 'pending')`,
 		documentID, driverID, documentType, documentURL, expiryTime,
 	)
 
 	if err != nil {
-		log.Printf("Error saving document: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to save document", nil))
+		logging.Errorf(tracing.Context(c), "Error saving document: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to save document", nil))
 		return
 	}
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse(gin.H{"id": documentID}, "Document uploaded successfully"))
+	c.JSON(http.StatusCreated, response.Success(gin.H{"id": documentID}, "Document uploaded successfully"))
 }
 
 // Helper function to upload document to storage service
-func uploadDocumentToStorage(file *multipart.FileHeader, documentType string, driverID string) (string, error) {
+func uploadDocumentToStorage(ctx context.Context, file *multipart.FileHeader, documentType string, driverID string) (string, error) {
 	storageURL := os.Getenv("STORAGE_SERVICE_URL")
 	if storageURL == "" {
 		storageURL = "http://localhost:3010"
@@ -237,13 +238,13 @@ func uploadDocumentToStorage(file *multipart.FileHeader, documentType string, dr
 	}
 
 	// Send request to storage service
-	req, err := http.NewRequest("POST", storageURL+"/api/v1/storage/upload/driver-document", body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, storageURL+"/api/v1/storage/upload/driver-document", body)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
+	client := &http.Client{Transport: tracing.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
@@ -279,16 +280,52 @@ func uploadDocumentToStorage(file *multipart.FileHeader, documentType string, dr
 func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	documentID := c.Param("id")
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`DELETE FROM driver_documents WHERE id = $1`,
 		documentID,
 	)
 
 	if err != nil {
-		log.Printf("Error deleting document: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to delete document", nil))
+		logging.Errorf(tracing.Context(c), "Error deleting document: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to delete document", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(nil, "Document deleted successfully"))
+	c.JSON(http.StatusOK, response.Success(nil, "Document deleted successfully"))
+}
+
+// ReviewDocument records an admin's decision on a driver document
+func (h *DocumentHandler) ReviewDocument(c *gin.Context) {
+	documentID := c.Param("id")
+
+	var req models.ReviewDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	tag, err := h.db.Exec(tracing.Context(c),
+		`UPDATE driver_documents
+		 SET verification_status = $1,
+		     verified_at = CASE WHEN $1 = 'verified' THEN NOW() ELSE NULL END
+		 WHERE id = $2`,
+		req.Status, documentID,
+	)
+
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error reviewing document: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to review document", nil))
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, response.Error("NOT_FOUND", "Document not found", nil))
+		return
+	}
+
+	logging.Infof(tracing.Context(c), "User %s marked document %s as %s", c.GetString("userId"), documentID, req.Status)
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"id":                 documentID,
+		"verificationStatus": req.Status,
+	}, "Document reviewed successfully"))
 }
@@ -1,16 +1,16 @@
 package handlers
 
 import (
-	"context"
-	"log"
 	"net/http"
 
 	"margwa/driver-service/models"
-	"margwa/driver-service/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
 )
 
 type DriverHandler struct {
@@ -26,7 +26,7 @@ func (h *DriverHandler) GetProfile(c *gin.Context) {
 	userID := c.GetString("userId")
 
 	var profile models.DriverProfile
-	err := h.db.QueryRow(context.Background(),
+	err := h.db.QueryRow(tracing.Context(c),
 		`SELECT id, user_id, license_number, license_expiry, license_image_url, 
 		 background_check_status, total_trips, total_earnings, average_rating, 
 		 is_online, current_latitude, current_longitude, last_location_update, 
@@ -42,20 +42,20 @@ func (h *DriverHandler) GetProfile(c *gin.Context) {
 	if err != nil {
 		// Profile doesn't exist, create one
 		newID := uuid.New()
-		_, createErr := h.db.Exec(context.Background(),
+		_, createErr := h.db.Exec(tracing.Context(c),
 			`INSERT INTO driver_profiles (id, user_id, background_check_status, total_trips, total_earnings, average_rating, is_online)
 			 VALUES ($1, $2, 'pending', 0, 0, 0, false)`,
 			newID, userID,
 		)
 
 		if createErr != nil {
-			log.Printf("Error creating driver profile: %v", createErr)
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to create driver profile", nil))
+			logging.Errorf(tracing.Context(c), "Error creating driver profile: %v", createErr)
+			c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to create driver profile", nil))
 			return
 		}
 
 		// Fetch the newly created profile
-		h.db.QueryRow(context.Background(),
+		h.db.QueryRow(tracing.Context(c),
 			`SELECT id, user_id, license_number, license_expiry, license_image_url, 
 			 background_check_status, total_trips, total_earnings, average_rating, 
 			 is_online, current_latitude, current_longitude, last_location_update, 
@@ -69,7 +69,7 @@ func (h *DriverHandler) GetProfile(c *gin.Context) {
 			&profile.CreatedAt, &profile.UpdatedAt)
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(profile, "Profile retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(profile, "Profile retrieved successfully"))
 }
 
 // UpdateProfile updates driver profile
@@ -78,11 +78,11 @@ func (h *DriverHandler) UpdateProfile(c *gin.Context) {
 
 	var req models.UpdateDriverProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 		return
 	}
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE driver_profiles SET 
 		 license_number = COALESCE($1, license_number),
 		 license_expiry = COALESCE($2, license_expiry),
@@ -93,14 +93,14 @@ func (h *DriverHandler) UpdateProfile(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error updating driver profile: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update profile", nil))
+		logging.Errorf(tracing.Context(c), "Error updating driver profile: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update profile", nil))
 		return
 	}
 
 	// Get updated profile
 	var profile models.DriverProfile
-	h.db.QueryRow(context.Background(),
+	h.db.QueryRow(tracing.Context(c),
 		`SELECT id, user_id, license_number, license_expiry, license_image_url, 
 		 background_check_status, total_trips, total_earnings, average_rating, 
 		 is_online, current_latitude, current_longitude, last_location_update, 
@@ -113,7 +113,7 @@ func (h *DriverHandler) UpdateProfile(c *gin.Context) {
 		&profile.CurrentLatitude, &profile.CurrentLongitude, &profile.LastLocationUpdate,
 		&profile.CreatedAt, &profile.UpdatedAt)
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(profile, "Profile updated successfully"))
+	c.JSON(http.StatusOK, response.Success(profile, "Profile updated successfully"))
 }
 
 // UpdateOnlineStatus toggles driver online/offline status
@@ -122,11 +122,11 @@ func (h *DriverHandler) UpdateOnlineStatus(c *gin.Context) {
 
 	var req models.UpdateOnlineStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 		return
 	}
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE driver_profiles SET 
 		 is_online = $1,
 		 updated_at = NOW()
@@ -135,12 +135,12 @@ func (h *DriverHandler) UpdateOnlineStatus(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error updating online status: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update status", nil))
+		logging.Errorf(tracing.Context(c), "Error updating online status: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update status", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"isOnline": req.IsOnline}, "Status updated successfully"))
+	c.JSON(http.StatusOK, response.Success(gin.H{"isOnline": req.IsOnline}, "Status updated successfully"))
 }
 
 // UpdateLocation updates driver's current location
@@ -149,11 +149,11 @@ func (h *DriverHandler) UpdateLocation(c *gin.Context) {
 
 	var req models.UpdateLocationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 		return
 	}
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE driver_profiles SET 
 		 current_latitude = $1,
 		 current_longitude = $2,
@@ -164,12 +164,12 @@ func (h *DriverHandler) UpdateLocation(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error updating location: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update location", nil))
+		logging.Errorf(tracing.Context(c), "Error updating location: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update location", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(nil, "Location updated successfully"))
+	c.JSON(http.StatusOK, response.Success(nil, "Location updated successfully"))
 }
 
 // GetStats retrieves driver statistics
@@ -177,15 +177,15 @@ func (h *DriverHandler) GetStats(c *gin.Context) {
 	userID := c.GetString("userId")
 
 	var stats models.DriverStats
-	err := h.db.QueryRow(context.Background(),
+	err := h.db.QueryRow(tracing.Context(c),
 		`SELECT total_trips, total_earnings, average_rating
 		 FROM driver_profiles WHERE user_id = $1`,
 		userID,
 	).Scan(&stats.TotalTrips, &stats.TotalEarnings, &stats.AverageRating)
 
 	if err != nil {
-		log.Printf("Error getting driver stats: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to get stats", nil))
+		logging.Errorf(tracing.Context(c), "Error getting driver stats: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to get stats", nil))
 		return
 	}
 
@@ -194,5 +194,5 @@ func (h *DriverHandler) GetStats(c *gin.Context) {
 	stats.MonthEarnings = "0"
 	stats.TodayEarnings = "0"
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(stats, "Stats retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(stats, "Stats retrieved successfully"))
 }
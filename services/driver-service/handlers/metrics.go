@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Domain metrics, served on /metrics next to the shared HTTP and pool metrics
+
+var vehiclesCreated = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "margwa_vehicles_created_total",
+	Help: "Vehicles registered by drivers.",
+})
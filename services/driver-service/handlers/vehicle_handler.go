@@ -8,18 +8,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"os"
 
 	"margwa/driver-service/models"
-	"margwa/driver-service/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
 )
 
 type VehicleHandler struct {
@@ -31,7 +32,7 @@ func NewVehicleHandler(db *pgxpool.Pool) *VehicleHandler {
 }
 
 // Storage service helper function
-func uploadToStorageService(file *multipart.FileHeader, documentType string, vehicleID string) (string, error) {
+func uploadToStorageService(ctx context.Context, file *multipart.FileHeader, documentType string, vehicleID string) (string, error) {
 	storageURL := os.Getenv("STORAGE_SERVICE_URL")
 	if storageURL == "" {
 		storageURL = "http://localhost:3010"
@@ -76,13 +77,13 @@ func uploadToStorageService(file *multipart.FileHeader, documentType string, veh
 	}
 
 	// Send request to storage service
-	req, err := http.NewRequest("POST", storageURL+"/api/v1/storage/upload/vehicle-document", body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, storageURL+"/api/v1/storage/upload/vehicle-document", body)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
+	client := &http.Client{Transport: tracing.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
@@ -139,14 +140,14 @@ func (h *VehicleHandler) getOrCreateDriverID(ctx context.Context, userID string)
 func (h *VehicleHandler) GetVehicles(c *gin.Context) {
 	userID := c.GetString("userId")
 
-	driverID, err := h.getOrCreateDriverID(context.Background(), userID)
+	driverID, err := h.getOrCreateDriverID(tracing.Context(c), userID)
 	if err != nil {
-		log.Printf("Error getting/creating driver ID: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to process driver profile", nil))
+		logging.Errorf(tracing.Context(c), "Error getting/creating driver ID: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to process driver profile", nil))
 		return
 	}
 
-	rows, err := h.db.Query(context.Background(),
+	rows, err := h.db.Query(tracing.Context(c),
 		`SELECT id, driver_id, vehicle_name, vehicle_type, vehicle_number, vehicle_color,
 		 manufacturing_year, total_seats, rc_number, rc_image_url, insurance_number,
 		 insurance_expiry, insurance_image_url, puc_number, puc_expiry, puc_image_url,
@@ -156,8 +157,8 @@ func (h *VehicleHandler) GetVehicles(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error getting vehicles: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to get vehicles", nil))
+		logging.Errorf(tracing.Context(c), "Error getting vehicles: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to get vehicles", nil))
 		return
 	}
 	defer rows.Close()
@@ -172,13 +173,13 @@ func (h *VehicleHandler) GetVehicles(c *gin.Context) {
 			&v.VerificationStatus, &v.IsActive, &v.CreatedAt, &v.UpdatedAt)
 
 		if err != nil {
-			log.Printf("Error scanning vehicle: %v", err)
+			logging.Errorf(tracing.Context(c), "Error scanning vehicle: %v", err)
 			continue
 		}
 		vehicles = append(vehicles, v)
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(vehicles, "Vehicles retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(vehicles, "Vehicles retrieved successfully"))
 }
 
 // GetVehicle retrieves a single vehicle by ID
@@ -186,7 +187,7 @@ func (h *VehicleHandler) GetVehicle(c *gin.Context) {
 	vehicleID := c.Param("id")
 
 	var vehicle models.Vehicle
-	err := h.db.QueryRow(context.Background(),
+	err := h.db.QueryRow(tracing.Context(c),
 		`SELECT id, driver_id, vehicle_name, vehicle_type, vehicle_number, vehicle_color,
 		 manufacturing_year, total_seats, rc_number, rc_image_url, insurance_number,
 		 insurance_expiry, insurance_image_url, puc_number, puc_expiry, puc_image_url,
@@ -200,12 +201,12 @@ func (h *VehicleHandler) GetVehicle(c *gin.Context) {
 		&vehicle.VerificationStatus, &vehicle.IsActive, &vehicle.CreatedAt, &vehicle.UpdatedAt)
 
 	if err != nil {
-		log.Printf("Error getting vehicle: %v", err)
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("NOT_FOUND", "Vehicle not found", nil))
+		logging.Errorf(tracing.Context(c), "Error getting vehicle: %v", err)
+		c.JSON(http.StatusNotFound, response.Error("NOT_FOUND", "Vehicle not found", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(vehicle, "Vehicle retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(vehicle, "Vehicle retrieved successfully"))
 }
 
 // CreateVehicle creates a new vehicle with multipart file upload
@@ -214,17 +215,17 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 
 	// Get driver ID
 	// Get driver ID
-	driverID, err := h.getOrCreateDriverID(context.Background(), userID)
+	driverID, err := h.getOrCreateDriverID(tracing.Context(c), userID)
 	if err != nil {
-		log.Printf("Error getting/creating driver ID: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to process driver profile", nil))
+		logging.Errorf(tracing.Context(c), "Error getting/creating driver ID: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to process driver profile", nil))
 		return
 	}
 
 	// Parse multipart form
 	err = c.Request.ParseMultipartForm(10 << 20) // 10MB max
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("PARSE_ERROR", "Failed to parse form data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("PARSE_ERROR", "Failed to parse form data", err.Error()))
 		return
 	}
 
@@ -249,9 +250,9 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 
 	// Upload RC document
 	if rcFile, err := c.FormFile("rcDocument"); err == nil {
-		url, uploadErr := uploadToStorageService(rcFile, "rc", vehicleID.String())
+		url, uploadErr := uploadToStorageService(tracing.Context(c), rcFile, "rc", vehicleID.String())
 		if uploadErr != nil {
-			log.Printf("Error uploading RC document: %v", uploadErr)
+			logging.Errorf(tracing.Context(c), "Error uploading RC document: %v", uploadErr)
 		} else {
 			rcImageURL = &url
 		}
@@ -259,9 +260,9 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 
 	// Upload Insurance document
 	if insuranceFile, err := c.FormFile("insuranceDocument"); err == nil {
-		url, uploadErr := uploadToStorageService(insuranceFile, "insurance", vehicleID.String())
+		url, uploadErr := uploadToStorageService(tracing.Context(c), insuranceFile, "insurance", vehicleID.String())
 		if uploadErr != nil {
-			log.Printf("Error uploading insurance document: %v", uploadErr)
+			logging.Errorf(tracing.Context(c), "Error uploading insurance document: %v", uploadErr)
 		} else {
 			insuranceImageURL = &url
 		}
@@ -269,9 +270,9 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 
 	// Upload PUC document
 	if pucFile, err := c.FormFile("pucDocument"); err == nil {
-		url, uploadErr := uploadToStorageService(pucFile, "puc", vehicleID.String())
+		url, uploadErr := uploadToStorageService(tracing.Context(c), pucFile, "puc", vehicleID.String())
 		if uploadErr != nil {
-			log.Printf("Error uploading PUC document: %v", uploadErr)
+			logging.Errorf(tracing.Context(c), "Error uploading PUC document: %v", uploadErr)
 		} else {
 			pucImageURL = &url
 		}
@@ -279,9 +280,9 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 
 	// Upload Permit document
 	if permitFile, err := c.FormFile("permitDocument"); err == nil {
-		url, uploadErr := uploadToStorageService(permitFile, "permit", vehicleID.String())
+		url, uploadErr := uploadToStorageService(tracing.Context(c), permitFile, "permit", vehicleID.String())
 		if uploadErr != nil {
-			log.Printf("Error uploading permit document: %v", uploadErr)
+			logging.Errorf(tracing.Context(c), "Error uploading permit document: %v", uploadErr)
 		} else {
 			permitImageURL = &url
 		}
@@ -296,7 +297,7 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 	}
 
 	// Insert vehicle into database
-	_, err = h.db.Exec(context.Background(),
+	_, err = h.db.Exec(tracing.Context(c),
 		`INSERT INTO vehicles (id, driver_id, vehicle_name, vehicle_type, vehicle_number, 
 		 vehicle_color, manufacturing_year, total_seats, rc_number, rc_image_url, 
 		 insurance_number, insurance_expiry, insurance_image_url, puc_number, puc_expiry, 
@@ -309,12 +310,12 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error creating vehicle: %v", err)
+		logging.Errorf(tracing.Context(c), "Error creating vehicle: %v", err)
 
 		// Check for duplicate vehicle number constraint violation
 		if strings.Contains(err.Error(), "vehicles_vehicle_number_unique") ||
 			strings.Contains(err.Error(), "duplicate key value") {
-			c.JSON(http.StatusConflict, utils.ErrorResponse(
+			c.JSON(http.StatusConflict, response.Error(
 				"DUPLICATE_VEHICLE",
 				"This vehicle number is already registered. Please check the number or contact support if this is your vehicle.",
 				nil,
@@ -322,13 +323,14 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to create vehicle", nil))
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to create vehicle", nil))
 		return
 	}
+	vehiclesCreated.Inc()
 
 	// Fetch the created vehicle
 	var vehicle models.Vehicle
-	h.db.QueryRow(context.Background(),
+	h.db.QueryRow(tracing.Context(c),
 		`SELECT id, driver_id, vehicle_name, vehicle_type, vehicle_number, vehicle_color,
 		 manufacturing_year, total_seats, rc_number, rc_image_url, insurance_number,
 		 insurance_expiry, insurance_image_url, puc_number, puc_expiry, puc_image_url,
@@ -341,31 +343,31 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 		&vehicle.PUCExpiry, &vehicle.PUCImageURL, &vehicle.PermitNumber, &vehicle.PermitImageURL,
 		&vehicle.VerificationStatus, &vehicle.IsActive, &vehicle.CreatedAt, &vehicle.UpdatedAt)
 
-	c.JSON(http.StatusCreated, utils.SuccessResponse(vehicle, "Vehicle created successfully"))
+	c.JSON(http.StatusCreated, response.Success(vehicle, "Vehicle created successfully"))
 }
 
 // UpdateVehicle updates an existing vehicle
 func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
 	vehicleID := c.Param("id")
-	log.Printf("Starting UpdateVehicle for ID: %s", vehicleID)
+	logging.Debugf(tracing.Context(c), "Starting UpdateVehicle for ID: %s", vehicleID)
 
 	// Check content type to decide how to parse
 	contentType := c.GetHeader("Content-Type")
-	log.Printf("UpdateVehicle received Content-Type: %s", contentType)
+	logging.Debugf(tracing.Context(c), "UpdateVehicle received Content-Type: %s", contentType)
 
 	var req models.UpdateVehicleRequest
 
 	if strings.Contains(contentType, "application/json") {
 		if err := c.ShouldBindJSON(&req); err != nil {
-			log.Printf("UpdateVehicle JSON bind failed: %v", err)
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+			logging.Debugf(tracing.Context(c), "UpdateVehicle JSON bind failed: %v", err)
+			c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 			return
 		}
 	} else {
 		// Parse multipart form
 		if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
-			log.Printf("UpdateVehicle multipart parse failed: %v", err)
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse("PARSE_ERROR", "Failed to parse form data", err.Error()))
+			logging.Debugf(tracing.Context(c), "UpdateVehicle multipart parse failed: %v", err)
+			c.JSON(http.StatusBadRequest, response.Error("PARSE_ERROR", "Failed to parse form data", err.Error()))
 			return
 		}
 
@@ -418,34 +420,34 @@ func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
 
 		// Handle file uploads
 		if file, err := c.FormFile("rcDocument"); err == nil {
-			url, uploadErr := uploadToStorageService(file, "rc", vehicleID)
+			url, uploadErr := uploadToStorageService(tracing.Context(c), file, "rc", vehicleID)
 			if uploadErr == nil {
 				req.RCImageURL = strPtr(url)
 			}
 		}
 		if file, err := c.FormFile("insuranceDocument"); err == nil {
-			url, uploadErr := uploadToStorageService(file, "insurance", vehicleID)
+			url, uploadErr := uploadToStorageService(tracing.Context(c), file, "insurance", vehicleID)
 			if uploadErr == nil {
 				req.InsuranceImageURL = strPtr(url)
 			}
 		}
 		if file, err := c.FormFile("pucDocument"); err == nil {
-			url, uploadErr := uploadToStorageService(file, "puc", vehicleID)
+			url, uploadErr := uploadToStorageService(tracing.Context(c), file, "puc", vehicleID)
 			if uploadErr == nil {
 				req.PUCImageURL = strPtr(url)
 			}
 		}
 		if file, err := c.FormFile("permitDocument"); err == nil {
-			url, uploadErr := uploadToStorageService(file, "permit", vehicleID)
+			url, uploadErr := uploadToStorageService(tracing.Context(c), file, "permit", vehicleID)
 			if uploadErr == nil {
 				req.PermitImageURL = strPtr(url)
 			}
 		}
 	}
 
-	log.Printf("UpdateVehicle request processed: %+v", req)
+	logging.Debugf(tracing.Context(c), "UpdateVehicle request processed: %+v", req)
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE vehicles SET 
 		 vehicle_name = COALESCE($1, vehicle_name),
 		 vehicle_type = COALESCE($2, vehicle_type),
@@ -471,14 +473,14 @@ func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error updating vehicle: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update vehicle", nil))
+		logging.Errorf(tracing.Context(c), "Error updating vehicle: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update vehicle", nil))
 		return
 	}
 
 	// Get updated vehicle
 	var vehicle models.Vehicle
-	err = h.db.QueryRow(context.Background(),
+	err = h.db.QueryRow(tracing.Context(c),
 		`SELECT id, driver_id, vehicle_name, vehicle_type, vehicle_number, vehicle_color,
 		 manufacturing_year, total_seats, rc_number, rc_image_url, insurance_number,
 		 insurance_expiry, insurance_image_url, puc_number, puc_expiry, puc_image_url,
@@ -492,48 +494,48 @@ func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
 		&vehicle.VerificationStatus, &vehicle.IsActive, &vehicle.CreatedAt, &vehicle.UpdatedAt)
 
 	if err != nil {
-		log.Printf("Error fetching updated vehicle: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to fetch updated vehicle", nil))
+		logging.Errorf(tracing.Context(c), "Error fetching updated vehicle: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to fetch updated vehicle", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(vehicle, "Vehicle updated successfully"))
+	c.JSON(http.StatusOK, response.Success(vehicle, "Vehicle updated successfully"))
 }
 
 // DeleteVehicle soft-deletes a vehicle
 func (h *VehicleHandler) DeleteVehicle(c *gin.Context) {
 	vehicleID := c.Param("id")
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE vehicles SET is_active = false, updated_at = NOW() WHERE id = $1`,
 		vehicleID,
 	)
 
 	if err != nil {
-		log.Printf("Error deleting vehicle: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to delete vehicle", nil))
+		logging.Errorf(tracing.Context(c), "Error deleting vehicle: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to delete vehicle", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(nil, "Vehicle deleted successfully"))
+	c.JSON(http.StatusOK, response.Success(nil, "Vehicle deleted successfully"))
 }
 
 // SetActiveVehicle activates a specific vehicle
 func (h *VehicleHandler) SetActiveVehicle(c *gin.Context) {
 	vehicleID := c.Param("id")
 
-	_, err := h.db.Exec(context.Background(),
+	_, err := h.db.Exec(tracing.Context(c),
 		`UPDATE vehicles SET is_active = true, updated_at = NOW() WHERE id = $1`,
 		vehicleID,
 	)
 
 	if err != nil {
-		log.Printf("Error activating vehicle: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to activate vehicle", nil))
+		logging.Errorf(tracing.Context(c), "Error activating vehicle: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to activate vehicle", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(nil, "Vehicle activated successfully"))
+	c.JSON(http.StatusOK, response.Success(nil, "Vehicle activated successfully"))
 }
 
 // SaveSeatConfiguration saves or updates seat configuration for a vehicle
@@ -543,43 +545,43 @@ func (h *VehicleHandler) SaveSeatConfiguration(c *gin.Context) {
 
 	// Verify vehicle ownership
 	var driverID uuid.UUID
-	err := h.db.QueryRow(context.Background(),
+	err := h.db.QueryRow(tracing.Context(c),
 		`SELECT driver_id FROM vehicles WHERE id = $1`,
 		vehicleID,
 	).Scan(&driverID)
 
 	if err != nil {
-		log.Printf("Error verifying vehicle ownership: %v", err)
-		c.JSON(http.StatusNotFound, utils.ErrorResponse("NOT_FOUND", "Vehicle not found", nil))
+		logging.Errorf(tracing.Context(c), "Error verifying vehicle ownership: %v", err)
+		c.JSON(http.StatusNotFound, response.Error("NOT_FOUND", "Vehicle not found", nil))
 		return
 	}
 
 	// Verify driver owns this vehicle
 	var ownerUserID uuid.UUID
-	err = h.db.QueryRow(context.Background(),
+	err = h.db.QueryRow(tracing.Context(c),
 		`SELECT user_id FROM driver_profiles WHERE id = $1`,
 		driverID,
 	).Scan(&ownerUserID)
 
 	if err != nil || ownerUserID.String() != userID {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse("FORBIDDEN", "You don't have permission to modify this vehicle", nil))
+		c.JSON(http.StatusForbidden, response.Error("FORBIDDEN", "You don't have permission to modify this vehicle", nil))
 		return
 	}
 
 	var req models.SaveSeatConfigRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
 		return
 	}
 
 	// Delete existing seat configuration
-	_, err = h.db.Exec(context.Background(),
+	_, err = h.db.Exec(tracing.Context(c),
 		`DELETE FROM vehicle_seat_configurations WHERE vehicle_id = $1`,
 		vehicleID,
 	)
 	if err != nil {
-		log.Printf("Error deleting old seat config: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to update seat configuration", nil))
+		logging.Errorf(tracing.Context(c), "Error deleting old seat config: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to update seat configuration", nil))
 		return
 	}
 
@@ -590,7 +592,7 @@ func (h *VehicleHandler) SaveSeatConfiguration(c *gin.Context) {
 			amenitiesJSON, _ = json.Marshal(seat.Amenities)
 		}
 
-		_, err = h.db.Exec(context.Background(),
+		_, err = h.db.Exec(tracing.Context(c),
 			`INSERT INTO vehicle_seat_configurations 
 			(vehicle_id, seat_id, row_number, position, is_available, seat_type, price, amenities)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
@@ -599,20 +601,20 @@ func (h *VehicleHandler) SaveSeatConfiguration(c *gin.Context) {
 		)
 
 		if err != nil {
-			log.Printf("Error inserting seat config: %v", err)
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to save seat configuration", nil))
+			logging.Errorf(tracing.Context(c), "Error inserting seat config: %v", err)
+			c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to save seat configuration", nil))
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(nil, "Seat configuration saved successfully"))
+	c.JSON(http.StatusOK, response.Success(nil, "Seat configuration saved successfully"))
 }
 
 // GetSeatConfiguration retrieves seat configuration for a vehicle
 func (h *VehicleHandler) GetSeatConfiguration(c *gin.Context) {
 	vehicleID := c.Param("id")
 
-	rows, err := h.db.Query(context.Background(),
+	rows, err := h.db.Query(tracing.Context(c),
 		`SELECT id, vehicle_id, seat_id, row_number, position, is_available, 
 		 seat_type, price, amenities, created_at, updated_at
 		 FROM vehicle_seat_configurations 
@@ -622,8 +624,8 @@ func (h *VehicleHandler) GetSeatConfiguration(c *gin.Context) {
 	)
 
 	if err != nil {
-		log.Printf("Error getting seat configuration: %v", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("DATABASE_ERROR", "Failed to get seat configuration", nil))
+		logging.Errorf(tracing.Context(c), "Error getting seat configuration: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to get seat configuration", nil))
 		return
 	}
 	defer rows.Close()
@@ -640,7 +642,7 @@ func (h *VehicleHandler) GetSeatConfiguration(c *gin.Context) {
 		)
 
 		if err != nil {
-			log.Printf("Error scanning seat: %v", err)
+			logging.Errorf(tracing.Context(c), "Error scanning seat: %v", err)
 			continue
 		}
 
@@ -652,5 +654,5 @@ func (h *VehicleHandler) GetSeatConfiguration(c *gin.Context) {
 		seats = append(seats, seat)
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(seats, "Seat configuration retrieved successfully"))
+	c.JSON(http.StatusOK, response.Success(seats, "Seat configuration retrieved successfully"))
 }
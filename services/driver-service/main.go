@@ -1,56 +1,152 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"os"
+	"time"
 
 	"margwa/driver-service/config"
-	"margwa/driver-service/database"
 	"margwa/driver-service/handlers"
-	"margwa/driver-service/middleware"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/health"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/metrics"
+	"github.com/margwa/shared/go/migrate"
+	"github.com/margwa/shared/go/postgres"
+	"github.com/margwa/shared/go/redisclient"
+	"github.com/margwa/shared/go/server"
+	"github.com/margwa/shared/go/tracing"
 )
 
+// schemaVersion is the newest shared/go/migrate migration this service
+// depends on (impersonation_audit)
+const schemaVersion = 13
+
 func main() {
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	runMigrations := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load("../../.env"); err != nil {
-		log.Println("No .env file found, using system environment variables")
-	}
+	env.Load("../../.env", ".env")
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
+	if *printConfig {
+		env.Print(os.Stdout, cfg)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
+
+	// JSON logs with request IDs; the standard log package writes through it too
+	logging.Setup("driver-service", cfg.LogLevel)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "driver-service", tracing.Config{
+		Exporter:    cfg.TraceExporter,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
 
 	// Initialize database
-	db, err := database.NewPostgresDB(cfg.DatabaseURL)
+	db, err := postgres.Connect(context.Background(), postgres.Config{
+		URL:      cfg.DatabaseURL,
+		MaxConns: 10,
+		MinConns: 2,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
+	// --migrate applies pending migrations and exits; otherwise refuse to
+	// start against a schema older than this build needs
+	if *runMigrations {
+		if err := migrate.Run(context.Background(), db); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := migrate.Require(context.Background(), db, schemaVersion); err != nil {
+		log.Fatalf("Incompatible database schema: %v", err)
+	}
+
 	log.Println("✅ Connected to database")
 
+	// Initialize Redis (token denylist). An unreachable server is logged
+	// rather than fatal; the denylist's fail mode decides what happens then.
+	redisClient, err := redisclient.New(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to configure Redis: %v", err)
+	}
+	defer redisClient.Close()
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		log.Printf("⚠️  Redis unavailable at startup: %v", err)
+	}
+
 	// Initialize handlers
 	driverHandler := handlers.NewDriverHandler(db)
 	vehicleHandler := handlers.NewVehicleHandler(db)
 	documentHandler := handlers.NewDocumentHandler(db)
 
+	// Access tokens verify against auth-service's JWKS, plus HS256 while migrating
+	var jwks *auth.JWKSCache
+	if cfg.JWKSURL != "" {
+		jwks = auth.NewJWKSCache(cfg.JWKSURL, 10*time.Minute)
+	}
+	hmacSecret := ""
+	if cfg.JWTAcceptHS256 {
+		hmacSecret = cfg.JWTSecret
+	}
+	keyFunc := auth.Keyfunc(hmacSecret, jwks)
+
+	// Prometheus metrics
+	m := metrics.New("driver-service")
+	m.WatchPostgres(db)
+	m.WatchRedis(redisClient)
+
 	// Setup Gin router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(tracing.Middleware("driver-service"))
+	router.Use(logging.Middleware())
+	router.Use(m.Middleware())
+	router.Use(auth.AuditImpersonation(db, "driver-service"))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "service": "driver-service"})
 	})
+	router.GET("/metrics", metrics.Handler())
+
+	// Liveness and readiness probes
+	probes := health.New("driver-service")
+	probes.Add("postgres", 2*time.Second, health.Postgres(db))
+	probes.Add("redis", 2*time.Second, health.Redis(redisClient))
+	probes.Add("storage", 3*time.Second, health.HTTP(cfg.StorageURL+"/health"))
+	router.GET("/livez", probes.Live)
+	router.GET("/readyz", probes.Ready)
 
 	// API v1 routes
 	api := router.Group("/api/v1")
+	requireAuth := auth.Middleware(keyFunc, auth.NewDenylist(redisClient, cfg.DenylistFailMode, 0))
 	{
 		// Driver profile routes (protected)
 		driver := api.Group("/driver")
-		driver.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+		// Only sessions signed in to the driver app, so a user registered in
+		// both apps cannot use their rider session here
+		driver.Use(requireAuth, auth.RequirePersona("driver"))
 		{
 			driver.GET("/profile", driverHandler.GetProfile)
 			driver.PUT("/profile", driverHandler.UpdateProfile)
@@ -75,11 +171,33 @@ func main() {
 			driver.POST("/documents/upload", documentHandler.UploadDocument)
 			driver.DELETE("/documents/:id", documentHandler.DeleteDocument)
 		}
+
+		// Admin routes are for staff in any persona and check permissions instead
+		admin := api.Group("/admin")
+		admin.Use(requireAuth)
+		{
+			admin.PUT("/documents/:id/review", auth.RequirePermission("drivers:verify"), documentHandler.ReviewDocument)
+		}
 	}
 
-	// Start server
+	// Start server; on SIGINT/SIGTERM readiness fails first, then in-flight
+	// requests finish before the listener closes
+	srv := server.New(router, server.Config{
+		Port:              cfg.Port,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		DrainDelay:        cfg.ShutdownDrainDelay,
+		ShutdownTimeout:   cfg.ShutdownTimeout,
+	})
+	srv.OnDrain(probes.Drain)
+	srv.OnShutdown("tracing", shutdownTracing)
+
 	log.Printf("🚀 Driver Service running on port %s", cfg.Port)
-	if err := router.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if err := srv.Run(); err != nil {
+		log.Fatalf("Server stopped: %v", err)
 	}
+
+	log.Println("Server exited")
 }
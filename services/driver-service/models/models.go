@@ -42,6 +42,10 @@ type UpdateLocationRequest struct {
 	Longitude float64 `json:"longitude" binding:"required"`
 }
 
+type ReviewDocumentRequest struct {
+	Status string `json:"status" binding:"required,oneof=verified rejected"`
+}
+
 type UpdateOnlineStatusRequest struct {
 	IsOnline bool `json:"isOnline" binding:"required"`
 }
@@ -137,20 +141,6 @@ type UploadDocumentRequest struct {
 }
 
 // Response Models
-type Response struct {
-	Success   bool        `json:"success"`
-	Data      interface{} `json:"data,omitempty"`
-	Message   string      `json:"message,omitempty"`
-	Error     *ErrorData  `json:"error,omitempty"`
-	Timestamp string      `json:"timestamp"`
-}
-
-type ErrorData struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
-}
-
 // Seat Configuration Models
 type SeatConfiguration struct {
 	ID          uuid.UUID `json:"id"`
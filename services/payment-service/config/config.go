@@ -1,11 +1,74 @@
 package config
 
-import "os"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-func GetEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	"github.com/margwa/shared/go/env"
+)
+
+type Config struct {
+	Port           string `env:"PAYMENT_SERVICE_PORT" default:"3007"`
+	Environment    string `env:"NODE_ENV" default:"development"`
+	DatabaseURL    string `env:"DATABASE_URL" required:"true" secret:"true"`
+	RedisURL       string `env:"REDIS_URL" default:"redis://localhost:6379"`
+	JWTSecret      string `env:"JWT_SECRET" default:"your-secret-key" secret:"true"`
+	JWKSURL        string `env:"JWKS_URL"`
+	JWTAcceptHS256 bool   `env:"JWT_ACCEPT_HS256" default:"true"`
+
+	// Revoked tokens must not move money, so the denylist fails closed here
+	DenylistFailMode string `env:"TOKEN_DENYLIST_FAIL_MODE" default:"closed" oneof:"open,closed"`
+
+	// Razorpay API credentials, required in production
+	RazorpayKeyID     string `env:"RAZORPAY_KEY_ID"`
+	RazorpayKeySecret string `env:"RAZORPAY_KEY_SECRET" secret:"true"`
+
+	LogLevel string `env:"LOG_LEVEL" default:"info" oneof:"debug,info,warn,error"`
+
+	// How long /readyz reports draining before the server stops accepting
+	// connections, so load balancers can take the instance out first
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	// Bounds waiting for in-flight requests, then background workers. Payment
+	// verifications wait on the gateway, so they get longer than elsewhere
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s"`
+
+	// HTTP server timeouts
+	HTTPReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	HTTPReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" default:"15s"`
+	HTTPWriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"30s"`
+	HTTPIdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"1m"`
+
+	// Tracing: otlp sends spans to OTEL_EXPORTER_OTLP_ENDPOINT, stdout prints them
+	TraceExporter    string  `env:"OTEL_TRACES_EXPORTER" default:"none" oneof:"otlp,stdout,none"`
+	TraceSampleRatio float64 `env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+}
+
+// LoadConfig reads the configuration from the environment and validates it.
+// The config is returned even when invalid so --print-config can show it.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+	err := env.Parse(cfg)
+	return cfg, errors.Join(err, cfg.validate())
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	if c.Environment == "production" {
+		if names := env.DefaultSecrets(c); len(names) > 0 {
+			errs = append(errs, fmt.Errorf("%s must be set in production", strings.Join(names, ", ")))
+		}
+		if c.RazorpayKeyID == "" || c.RazorpayKeySecret == "" {
+			errs = append(errs, errors.New("RAZORPAY_KEY_ID and RAZORPAY_KEY_SECRET must be set in production"))
+		}
+	}
+	if !c.JWTAcceptHS256 && c.JWKSURL == "" {
+		errs = append(errs, errors.New("JWKS_URL is required when JWT_ACCEPT_HS256 is false"))
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1, got %g", c.TraceSampleRatio))
 	}
-	return value
+	return errors.Join(errs...)
 }
@@ -1,29 +0,0 @@
-package database
-
-import (
-	"context"
-	"os"
-
-	"github.com/redis/go-redis/v9"
-)
-
-func InitRedis() *redis.Client {
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "redis://localhost:6379"
-	}
-
-	opt, err := redis.ParseURL(redisURL)
-	if err != nil {
-		panic("Failed to parse Redis URL: " + err.Error())
-	}
-
-	client := redis.NewClient(opt)
-
-	// Test connection
-	if err := client.Ping(context.Background()).Err(); err != nil {
-		panic("Failed to connect to Redis: " + err.Error())
-	}
-
-	return client
-}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/margwa/payment-service/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Domain metrics, served on /metrics next to the shared HTTP and pool metrics
+
+var paymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "margwa_payments_total",
+	Help: "Payments moved into each status, by payment method.",
+}, []string{"status", "method"})
+
+// recordPayment counts a payment that has just been written with its new
+// status. Methods outside the known set are counted as "other" so a client
+// cannot create new series.
+func recordPayment(payment models.Payment) {
+	method := payment.PaymentMethod
+	switch method {
+	case models.PaymentMethodCash, models.PaymentMethodCard, models.PaymentMethodUPI, models.PaymentMethodWallet:
+	default:
+		method = "other"
+	}
+	paymentsTotal.WithLabelValues(string(payment.PaymentStatus), string(method)).Inc()
+}
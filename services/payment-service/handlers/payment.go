@@ -1,15 +1,20 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/payment-service/config"
 	"github.com/margwa/payment-service/models"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
 	razorpay "github.com/razorpay/razorpay-go"
 	"github.com/redis/go-redis/v9"
 )
@@ -20,9 +25,8 @@ type PaymentHandler struct {
 	razorpayClient *razorpay.Client
 }
 
-func NewPaymentHandler(db *pgxpool.Pool, redis *redis.Client) *PaymentHandler {
-	// Initialize Razorpay client (configure in production)
-	client := razorpay.NewClient("key_id", "key_secret")
+func NewPaymentHandler(db *pgxpool.Pool, redis *redis.Client, cfg *config.Config) *PaymentHandler {
+	client := razorpay.NewClient(cfg.RazorpayKeyID, cfg.RazorpayKeySecret)
 
 	return &PaymentHandler{
 		db:             db,
@@ -35,14 +39,14 @@ func NewPaymentHandler(db *pgxpool.Pool, redis *redis.Client) *PaymentHandler {
 func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 	var req models.InitiatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "VALIDATION_ERROR",
-				Message: "Invalid request data",
-				Details: err.Error(),
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", err.Error()))
+		return
+	}
+
+	// Payments are always made by the caller
+	payerID := c.GetString("userId")
+	if req.PayerID != uuid.Nil && req.PayerID.String() != payerID {
+		c.JSON(http.StatusForbidden, response.Error("FORBIDDEN", "You can only make payments as yourself", nil))
 		return
 	}
 
@@ -56,11 +60,11 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 
 	var payment models.Payment
 	err := h.db.QueryRow(
-		context.Background(),
+		tracing.Context(c),
 		query,
 		paymentID,
 		req.BookingID,
-		req.PayerID,
+		payerID,
 		req.Amount,
 		req.PaymentMethod,
 		models.PaymentStatusPending,
@@ -76,15 +80,10 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to initiate payment",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to initiate payment", nil))
 		return
 	}
+	recordPayment(payment)
 
 	// For UPI/Card, create Razorpay order
 	var razorpayOrderID string
@@ -93,27 +92,17 @@ func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 		razorpayOrderID = fmt.Sprintf("order_%s", paymentID.String()[:8])
 	}
 
-	c.JSON(http.StatusCreated, models.APIResponse{
-		Success: true,
-		Data: gin.H{
-			"payment":           payment,
-			"razorpay_order_id": razorpayOrderID,
-		},
-		Message: "Payment initiated successfully",
-	})
+	c.JSON(http.StatusCreated, response.Success(gin.H{
+		"payment":           payment,
+		"razorpay_order_id": razorpayOrderID,
+	}, "Payment initiated successfully"))
 }
 
 // POST /payments/verify - Verify payment
 func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 	var req models.VerifyPaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "VALIDATION_ERROR",
-				Message: "Invalid request data",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", nil))
 		return
 	}
 
@@ -127,7 +116,7 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 
 	var payment models.Payment
 	err := h.db.QueryRow(
-		context.Background(),
+		tracing.Context(c),
 		query,
 		models.PaymentStatusCompleted,
 		req.TransactionID,
@@ -147,21 +136,12 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to verify payment",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to verify payment", nil))
 		return
 	}
+	recordPayment(payment)
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    payment,
-		Message: "Payment verified successfully",
-	})
+	c.JSON(http.StatusOK, response.Success(payment, "Payment verified successfully"))
 }
 
 // GET /payments/:bookingId - Get payment by booking ID
@@ -176,7 +156,7 @@ func (h *PaymentHandler) GetPaymentByBooking(c *gin.Context) {
 	`
 
 	var payment models.Payment
-	err := h.db.QueryRow(context.Background(), query, bookingID).Scan(
+	err := h.db.QueryRow(tracing.Context(c), query, bookingID).Scan(
 		&payment.ID,
 		&payment.BookingID,
 		&payment.PayerID,
@@ -191,34 +171,18 @@ func (h *PaymentHandler) GetPaymentByBooking(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "NOT_FOUND",
-				Message: "Payment not found",
-			},
-		})
+		c.JSON(http.StatusNotFound, response.Error("NOT_FOUND", "Payment not found", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    payment,
-		Message: "Payment retrieved successfully",
-	})
+	c.JSON(http.StatusOK, response.Success(payment, "Payment retrieved successfully"))
 }
 
 // POST /payments/refund - Process refund
 func (h *PaymentHandler) ProcessRefund(c *gin.Context) {
 	var req models.RefundRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "VALIDATION_ERROR",
-				Message: "Invalid request data",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", nil))
 		return
 	}
 
@@ -226,12 +190,12 @@ func (h *PaymentHandler) ProcessRefund(c *gin.Context) {
 		UPDATE payments
 		SET payment_status = $1, refunded_at = $2
 		WHERE id = $3 AND payment_status = $4
-		RETURNING id, booking_id, payer_id, amount, payment_status, refunded_at
+		RETURNING id, booking_id, payer_id, amount, payment_method, payment_status, refunded_at
 	`
 
 	var payment models.Payment
 	err := h.db.QueryRow(
-		context.Background(),
+		tracing.Context(c),
 		query,
 		models.PaymentStatusRefunded,
 		time.Now(),
@@ -242,26 +206,18 @@ func (h *PaymentHandler) ProcessRefund(c *gin.Context) {
 		&payment.BookingID,
 		&payment.PayerID,
 		&payment.Amount,
+		&payment.PaymentMethod,
 		&payment.PaymentStatus,
 		&payment.RefundedAt,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "REFUND_FAILED",
-				Message: "Failed to process refund",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("REFUND_FAILED", "Failed to process refund", nil))
 		return
 	}
+	recordPayment(payment)
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    payment,
-		Message: "Refund processed successfully",
-	})
+	c.JSON(http.StatusOK, response.Success(payment, "Refund processed successfully"))
 }
 
 // POST /payments/webhook - Handle payment gateway webhooks
@@ -278,13 +234,7 @@ func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
 func (h *PaymentHandler) CalculateEarnings(c *gin.Context) {
 	var req models.CalculateEarningsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "VALIDATION_ERROR",
-				Message: "Invalid request data",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", nil))
 		return
 	}
 
@@ -301,7 +251,7 @@ func (h *PaymentHandler) CalculateEarnings(c *gin.Context) {
 
 	var earning models.Earning
 	err := h.db.QueryRow(
-		context.Background(),
+		tracing.Context(c),
 		query,
 		earningID,
 		req.DriverID,
@@ -325,21 +275,11 @@ func (h *PaymentHandler) CalculateEarnings(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to calculate earnings",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to calculate earnings", nil))
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.APIResponse{
-		Success: true,
-		Data:    earning,
-		Message: "Earnings calculated successfully",
-	})
+	c.JSON(http.StatusCreated, response.Success(earning, "Earnings calculated successfully"))
 }
 
 // GET /earnings/driver/:driverId - Get driver earnings
@@ -355,15 +295,9 @@ func (h *PaymentHandler) GetDriverEarnings(c *gin.Context) {
 		LIMIT 50
 	`
 
-	rows, err := h.db.Query(context.Background(), query, driverID)
+	rows, err := h.db.Query(tracing.Context(c), query, driverID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "DATABASE_ERROR",
-				Message: "Failed to fetch earnings",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("DATABASE_ERROR", "Failed to fetch earnings", nil))
 		return
 	}
 	defer rows.Close()
@@ -389,24 +323,34 @@ func (h *PaymentHandler) GetDriverEarnings(c *gin.Context) {
 		earnings = append(earnings, earning)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data:    earnings,
-		Message: "Earnings retrieved successfully",
-	})
+	c.JSON(http.StatusOK, response.Success(earnings, "Earnings retrieved successfully"))
 }
 
 // POST /earnings/withdraw - Process withdrawal
 func (h *PaymentHandler) ProcessWithdrawal(c *gin.Context) {
 	var req models.WithdrawalRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "VALIDATION_ERROR",
-				Message: "Invalid request data",
-			},
-		})
+		c.JSON(http.StatusBadRequest, response.Error("VALIDATION_ERROR", "Invalid request data", nil))
+		return
+	}
+
+	// Only the caller's own earnings can be withdrawn
+	var driverID uuid.UUID
+	err := h.db.QueryRow(tracing.Context(c),
+		`SELECT id FROM driver_profiles WHERE user_id = $1`,
+		c.GetString("userId"),
+	).Scan(&driverID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, response.Error("DRIVER_NOT_FOUND", "Driver profile not found", nil))
+		return
+	}
+	if err != nil {
+		logging.Errorf(tracing.Context(c), "Error finding driver profile for withdrawal: %v", err)
+		c.JSON(http.StatusInternalServerError, response.Error("WITHDRAWAL_FAILED", "Failed to process withdrawal", nil))
+		return
+	}
+	if req.DriverID != uuid.Nil && req.DriverID != driverID {
+		c.JSON(http.StatusForbidden, response.Error("FORBIDDEN", "You can only withdraw your own earnings", nil))
 		return
 	}
 
@@ -420,22 +364,16 @@ func (h *PaymentHandler) ProcessWithdrawal(c *gin.Context) {
 
 	var updatedIDs []uuid.UUID
 	rows, err := h.db.Query(
-		context.Background(),
+		tracing.Context(c),
 		query,
 		models.WithdrawalStatusWithdrawn,
 		time.Now(),
-		req.DriverID,
+		driverID,
 		models.WithdrawalStatusPending,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error: &models.APIError{
-				Code:    "WITHDRAWAL_FAILED",
-				Message: "Failed to process withdrawal",
-			},
-		})
+		c.JSON(http.StatusInternalServerError, response.Error("WITHDRAWAL_FAILED", "Failed to process withdrawal", nil))
 		return
 	}
 	defer rows.Close()
@@ -446,12 +384,8 @@ func (h *PaymentHandler) ProcessWithdrawal(c *gin.Context) {
 		updatedIDs = append(updatedIDs, id)
 	}
 
-	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data: gin.H{
-			"withdrawn_count": len(updatedIDs),
-			"amount":          req.Amount,
-		},
-		Message: "Withdrawal processed successfully",
-	})
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"withdrawn_count": len(updatedIDs),
+		"amount":          req.Amount,
+	}, "Withdrawal processed successfully"))
 }
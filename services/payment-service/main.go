@@ -1,47 +1,113 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"github.com/margwa/payment-service/config"
-	"github.com/margwa/payment-service/database"
 	"github.com/margwa/payment-service/handlers"
+	"github.com/margwa/shared/go/auth"
+	"github.com/margwa/shared/go/env"
+	"github.com/margwa/shared/go/health"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/metrics"
+	"github.com/margwa/shared/go/migrate"
+	"github.com/margwa/shared/go/postgres"
+	"github.com/margwa/shared/go/redisclient"
+	"github.com/margwa/shared/go/server"
+	"github.com/margwa/shared/go/tracing"
 )
 
+// schemaVersion is the newest shared/go/migrate migration this service
+// depends on (impersonation_audit)
+const schemaVersion = 13
+
 func main() {
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	runMigrations := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load("../../.env"); err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	env.Load("../../.env")
+
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if *printConfig {
+		env.Print(os.Stdout, cfg)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
+
+	// JSON logs with request IDs; the standard log package writes through it too
+	logging.Setup("payment-service", cfg.LogLevel)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), "payment-service", tracing.Config{
+		Exporter:    cfg.TraceExporter,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
 
 	// Initialize database
-	db, err := database.InitDB()
+	db, err := postgres.Connect(context.Background(), postgres.Config{URL: cfg.DatabaseURL})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer db.Close()
 
+	// --migrate applies pending migrations and exits; otherwise refuse to
+	// start against a schema older than this build needs
+	if *runMigrations {
+		if err := migrate.Run(context.Background(), db); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := migrate.Require(context.Background(), db, schemaVersion); err != nil {
+		log.Fatalf("Incompatible database schema: %v", err)
+	}
+
 	// Initialize Redis
-	redisClient := database.InitRedis()
+	redisClient, err := redisclient.Connect(context.Background(), cfg.RedisURL)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis:", err)
+	}
 	defer redisClient.Close()
 
+	// Prometheus metrics
+	m := metrics.New("payment-service")
+	m.WatchPostgres(db)
+	m.WatchRedis(redisClient)
+
 	// Initialize Gin router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(tracing.Middleware("payment-service"))
+	router.Use(logging.Middleware())
+	router.Use(m.Middleware())
 
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
+	router.Use(auth.AuditImpersonation(db, "payment-service"))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -51,17 +117,42 @@ func main() {
 			"service":   "payment-service",
 		})
 	})
+	router.GET("/metrics", metrics.Handler())
+
+	// Liveness and readiness probes
+	probes := health.New("payment-service")
+	probes.Add("postgres", 2*time.Second, health.Postgres(db))
+	probes.Add("redis", 2*time.Second, health.Redis(redisClient))
+	router.GET("/livez", probes.Live)
+	router.GET("/readyz", probes.Ready)
 
 	// Initialize payment handler
-	paymentHandler := handlers.NewPaymentHandler(db, redisClient)
+	paymentHandler := handlers.NewPaymentHandler(db, redisClient, cfg)
+
+	// Access tokens verify against auth-service's JWKS, plus HS256 while migrating
+	var jwks *auth.JWKSCache
+	if cfg.JWKSURL != "" {
+		jwks = auth.NewJWKSCache(cfg.JWKSURL, 10*time.Minute)
+	}
+	hmacSecret := ""
+	if cfg.JWTAcceptHS256 {
+		hmacSecret = cfg.JWTSecret
+	}
+	requireAuth := auth.Middleware(auth.Keyfunc(hmacSecret, jwks), auth.NewDenylist(redisClient, cfg.DenylistFailMode, 0))
+	// Impersonation tokens must never move money
+	noImpersonation := auth.BlockImpersonation()
+	// Payouts need a driver session that passed a second factor beyond the
+	// SMS OTP
+	requireDriver := auth.RequirePersona("driver")
+	requireDriverMFA := auth.RequireMFA("driver", "both")
 
 	// Payment routes
 	payments := router.Group("/payments")
 	{
-		payments.POST("/initiate", paymentHandler.InitiatePayment)
-		payments.POST("/verify", paymentHandler.VerifyPayment)
+		payments.POST("/initiate", requireAuth, noImpersonation, paymentHandler.InitiatePayment)
+		payments.POST("/verify", requireAuth, noImpersonation, paymentHandler.VerifyPayment)
 		payments.GET("/:bookingId", paymentHandler.GetPaymentByBooking)
-		payments.POST("/refund", paymentHandler.ProcessRefund)
+		payments.POST("/refund", requireAuth, noImpersonation, auth.RequirePermission("payments:refund"), paymentHandler.ProcessRefund)
 		payments.POST("/webhook", paymentHandler.HandleWebhook)
 	}
 
@@ -70,15 +161,28 @@ func main() {
 	{
 		earnings.POST("/calculate", paymentHandler.CalculateEarnings)
 		earnings.GET("/driver/:driverId", paymentHandler.GetDriverEarnings)
-		earnings.POST("/withdraw", paymentHandler.ProcessWithdrawal)
+		earnings.POST("/withdraw", requireAuth, noImpersonation, requireDriver, requireDriverMFA, paymentHandler.ProcessWithdrawal)
 	}
 
-	// Start server
-	port := config.GetEnv("PAYMENT_SERVICE_PORT", "3007")
-	log.Printf("💳 Payment Service running on port %s\n", port)
-	log.Printf("Environment: %s\n", config.GetEnv("NODE_ENV", "development"))
+	// Start server; on SIGINT/SIGTERM readiness fails first, then in-flight
+	// requests finish before the listener closes
+	srv := server.New(router, server.Config{
+		Port:              cfg.Port,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		DrainDelay:        cfg.ShutdownDrainDelay,
+		ShutdownTimeout:   cfg.ShutdownTimeout,
+	})
+	srv.OnDrain(probes.Drain)
+	srv.OnShutdown("tracing", shutdownTracing)
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	log.Printf("💳 Payment Service running on port %s", cfg.Port)
+	log.Printf("Environment: %s", cfg.Environment)
+	if err := srv.Run(); err != nil {
+		log.Fatalf("Server stopped: %v", err)
 	}
+
+	log.Println("Server exited")
 }
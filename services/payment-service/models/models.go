@@ -56,22 +56,11 @@ type Earning struct {
 	CreatedAt          time.Time        `json:"created_at"`
 }
 
-type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Error   *APIError   `json:"error,omitempty"`
-}
-
-type APIError struct {
-	Code    string      `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
-}
-
+// InitiatePaymentRequest starts a payment by the caller. PayerID is optional
+// and must match the caller when given.
 type InitiatePaymentRequest struct {
 	BookingID     uuid.UUID     `json:"booking_id" binding:"required"`
-	PayerID       uuid.UUID     `json:"payer_id" binding:"required"`
+	PayerID       uuid.UUID     `json:"payer_id"`
 	Amount        float64       `json:"amount" binding:"required,gt=0"`
 	PaymentMethod PaymentMethod `json:"payment_method" binding:"required"`
 }
@@ -93,7 +82,9 @@ type CalculateEarningsRequest struct {
 	Amount    float64   `json:"amount" binding:"required,gt=0"`
 }
 
+// WithdrawalRequest withdraws the caller's own earnings. DriverID is
+// optional and must match the caller's driver profile when given.
 type WithdrawalRequest struct {
-	DriverID uuid.UUID `json:"driver_id" binding:"required"`
+	DriverID uuid.UUID `json:"driver_id"`
 	Amount   float64   `json:"amount" binding:"required,gt=0"`
 }
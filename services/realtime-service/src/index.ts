@@ -3,12 +3,12 @@ import { createServer } from 'http';
 import { Server, Socket } from 'socket.io';
 import { createClient } from 'redis';
 import { createAdapter } from '@socket.io/redis-adapter';
-import jwt from 'jsonwebtoken';
 import dotenv from 'dotenv';
 import cors from 'cors';
 import { logger } from './utils/logger';
 import { setupSocketHandlers } from './handlers/socketHandlers';
 import { JWTPayload } from '../../../shared/types';
+import { verifyAccessToken } from '../../../shared/utils/jwt';
 
 dotenv.config({ path: '../../.env' });
 
@@ -56,20 +56,16 @@ io.use((socket: Socket, next) => {
         return next(new Error('Authentication required'));
     }
 
-    try {
-        const secret = process.env.JWT_SECRET;
-        if (!secret) {
-            throw new Error('JWT_SECRET not configured');
-        }
-
-        const decoded = jwt.verify(token, secret) as JWTPayload;
-        socket.data.user = decoded;
-        logger.info(`User authenticated: ${decoded.userId}`);
-        next();
-    } catch (error) {
-        logger.warn('WebSocket authentication failed:', error);
-        next(new Error('Invalid token'));
-    }
+    verifyAccessToken(token)
+        .then((decoded) => {
+            socket.data.user = decoded;
+            logger.info(`User authenticated: ${decoded.userId}`);
+            next();
+        })
+        .catch((error) => {
+            logger.warn('WebSocket authentication failed:', error);
+            next(new Error('Invalid token'));
+        });
 });
 
 // Socket event handlers
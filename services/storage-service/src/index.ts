@@ -2,6 +2,7 @@ import express from 'express';
 import cors from 'cors';
 import dotenv from 'dotenv';
 import uploadRoutes from './routes/upload';
+import fileRoutes from './routes/files';
 import { testMinIOConnection } from './config/minio';
 
 dotenv.config();
@@ -20,6 +21,7 @@ app.get('/health', (req, res) => {
 
 // Routes
 app.use('/api/v1/storage/upload', uploadRoutes);
+app.use('/api/v1/storage/files', fileRoutes);
 
 // Error handling
 app.use((err: any, req: express.Request, res: express.Response, next: express.NextFunction) => {
@@ -0,0 +1,82 @@
+import { Router, Request, Response, NextFunction } from 'express';
+import { minioService } from '../services/minioService';
+import { BUCKETS } from '../config/minio';
+
+const router = Router();
+
+const knownBuckets: string[] = Object.values(BUCKETS);
+
+/**
+ * Internal routes are called by other services, not through the gateway,
+ * and must carry the shared STORAGE_INTERNAL_TOKEN
+ */
+function requireInternalToken(req: Request, res: Response, next: NextFunction) {
+    const token = process.env.STORAGE_INTERNAL_TOKEN;
+    if (!token || req.header('X-Internal-Token') !== token) {
+        return res.status(401).json({
+            success: false,
+            error: { code: 'UNAUTHORIZED', message: 'Invalid internal token' },
+        });
+    }
+    next();
+}
+
+/**
+ * Split a URL from getPublicUrl into its bucket and object name. URLs that
+ * do not point into one of our buckets return null.
+ */
+function parseObjectUrl(url: string): { bucket: string; fileId: string } | null {
+    let path: string;
+    try {
+        path = new URL(url).pathname;
+    } catch {
+        return null;
+    }
+    const [, bucket, ...rest] = path.split('/');
+    const fileId = decodeURIComponent(rest.join('/'));
+    if (!knownBuckets.includes(bucket) || !fileId) {
+        return null;
+    }
+    return { bucket, fileId };
+}
+
+/**
+ * Delete stored files, e.g. those of a purged account. Missing objects and
+ * URLs outside our buckets count as deleted; the rest are reported back so
+ * the caller can retry them.
+ * POST /api/v1/storage/files/delete
+ */
+router.post('/delete', requireInternalToken, async (req: Request, res: Response) => {
+    const { urls } = req.body;
+    if (!Array.isArray(urls) || !urls.every((u) => typeof u === 'string')) {
+        return res.status(400).json({
+            success: false,
+            error: { code: 'MISSING_DATA', message: 'urls must be an array of strings' },
+        });
+    }
+
+    const failed: Array<{ url: string; error: string }> = [];
+    for (const url of urls as string[]) {
+        const object = parseObjectUrl(url);
+        if (!object) {
+            continue;
+        }
+        try {
+            await minioService.deleteFile(object.bucket, object.fileId);
+            // Avatar uploads also store a thumbnail under thumb_<fileId>
+            if (object.bucket === BUCKETS.AVATARS && !object.fileId.startsWith('thumb_')) {
+                await minioService.deleteFile(object.bucket, `thumb_${object.fileId}`);
+            }
+        } catch (error: any) {
+            console.error('File deletion error:', error);
+            failed.push({ url, error: error.message });
+        }
+    }
+
+    res.json({
+        success: true,
+        data: { deleted: urls.length - failed.length, failed },
+    });
+});
+
+export default router;
@@ -0,0 +1,34 @@
+import { pgTable, uuid, varchar, text, timestamp, integer, jsonb } from 'drizzle-orm/pg-core';
+import { users } from './users';
+
+// Impersonation Audit Table: one row per impersonation token issued ('issued')
+// and per request made with one ('used')
+export const impersonationAudit = pgTable('impersonation_audit', {
+    id: uuid('id').primaryKey().defaultRandom(),
+    actorId: uuid('actor_id').notNull().references(() => users.id),
+    targetUserId: uuid('target_user_id').notNull().references(() => users.id),
+    tokenId: varchar('token_id', { length: 64 }).notNull(),
+    event: varchar('event', { length: 20 }).notNull(),
+    reason: text('reason'),
+    service: varchar('service', { length: 50 }).notNull(),
+    method: varchar('method', { length: 10 }).notNull(),
+    path: text('path').notNull(),
+    statusCode: integer('status_code').notNull(),
+    ipAddress: varchar('ip_address', { length: 45 }),
+    createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
+});
+
+// Auth Events Table: security audit log written by auth-service
+export const authEvents = pgTable('auth_events', {
+    id: uuid('id').primaryKey().defaultRandom(),
+    userId: uuid('user_id').notNull().references(() => users.id, { onDelete: 'cascade' }),
+    eventType: varchar('event_type', { length: 50 }).notNull(),
+    ipAddress: varchar('ip_address', { length: 45 }),
+    deviceId: varchar('device_id', { length: 255 }),
+    userAgent: text('user_agent'),
+    metadata: jsonb('metadata'),
+    createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
+});
+
+export type ImpersonationAudit = typeof impersonationAudit.$inferSelect;
+export type AuthEvent = typeof authEvents.$inferSelect;
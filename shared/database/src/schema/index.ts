@@ -6,3 +6,6 @@ export * from './bookings';
 export * from './payments';
 export * from './chat';
 export * from './notifications';
+export * from './roles';
+export * from './audit';
+export * from './mfa';
@@ -0,0 +1,23 @@
+import { pgTable, uuid, text, timestamp, bigint } from 'drizzle-orm/pg-core';
+import { users } from './users';
+
+// User TOTP Table: one authenticator app per user
+export const userTotp = pgTable('user_totp', {
+    userId: uuid('user_id').primaryKey().references(() => users.id, { onDelete: 'cascade' }),
+    secretEncrypted: text('secret_encrypted').notNull(),
+    confirmedAt: timestamp('confirmed_at', { withTimezone: true }),
+    lastUsedCounter: bigint('last_used_counter', { mode: 'number' }).notNull().default(0),
+    createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
+});
+
+// MFA Recovery Codes Table: single-use codes stored as HMAC digests
+export const mfaRecoveryCodes = pgTable('mfa_recovery_codes', {
+    id: uuid('id').primaryKey().defaultRandom(),
+    userId: uuid('user_id').notNull().references(() => users.id, { onDelete: 'cascade' }),
+    codeHash: text('code_hash').notNull(),
+    usedAt: timestamp('used_at', { withTimezone: true }),
+    createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
+});
+
+export type UserTotp = typeof userTotp.$inferSelect;
+export type MfaRecoveryCode = typeof mfaRecoveryCodes.$inferSelect;
@@ -11,6 +11,7 @@ export const users = pgTable('users', {
     phoneCountryCode: varchar('phone_country_code', { length: 5 }).notNull().default('+91'),
     fullName: varchar('full_name', { length: 255 }),
     email: varchar('email', { length: 255 }),
+    emailVerifiedAt: timestamp('email_verified_at', { withTimezone: true }),
     profileImageUrl: text('profile_image_url'),
     dob: timestamp('dob', { withTimezone: true }),
     gender: varchar('gender', { length: 20 }),
@@ -22,6 +23,8 @@ export const users = pgTable('users', {
     createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
     updatedAt: timestamp('updated_at', { withTimezone: true }).notNull().defaultNow(),
     lastLoginAt: timestamp('last_login_at', { withTimezone: true }),
+    deletionScheduledAt: timestamp('deletion_scheduled_at', { withTimezone: true }),
+    deletedAt: timestamp('deleted_at', { withTimezone: true }),
 });
 
 // OTP Verifications Table
@@ -29,10 +32,14 @@ export const otpVerifications = pgTable('otp_verifications', {
     id: uuid('id').primaryKey().defaultRandom(),
     userId: uuid('user_id').references(() => users.id, { onDelete: 'cascade' }),
     phoneNumber: varchar('phone_number', { length: 20 }).notNull(),
-    otpCode: varchar('otp_code', { length: 6 }).notNull(),
+    otpCode: varchar('otp_code', { length: 128 }).notNull(), // HMAC-SHA256 digest
+    purpose: varchar('purpose', { length: 20 }).notNull().default('login'),
     expiresAt: timestamp('expires_at', { withTimezone: true }).notNull(),
     verifiedAt: timestamp('verified_at', { withTimezone: true }),
     attempts: integer('attempts').notNull().default(0),
+    deliveryChannel: varchar('delivery_channel', { length: 10 }).notNull().default('sms'),
+    deliveryStatus: varchar('delivery_status', { length: 20 }).notNull().default('pending'),
+    deliveryError: text('delivery_error'),
     createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
 });
 
@@ -48,6 +55,12 @@ export const sessions = pgTable('sessions', {
     expiresAt: timestamp('expires_at', { withTimezone: true }).notNull(),
     createdAt: timestamp('created_at', { withTimezone: true }).notNull().defaultNow(),
     lastUsedAt: timestamp('last_used_at', { withTimezone: true }),
+    persona: varchar('persona', { length: 10 }).notNull().default('client'),
+    riskScore: integer('risk_score'),
+    riskLevel: varchar('risk_level', { length: 10 }),
+    riskReasons: text('risk_reasons').array(),
+    stepUpMethod: varchar('step_up_method', { length: 20 }),
+    mfaVerifiedAt: timestamp('mfa_verified_at', { withTimezone: true }),
 });
 
 // Type exports
@@ -0,0 +1,68 @@
+// Package auth verifies the access tokens auth-service issues and provides
+// the gin middleware the services put in front of their routes.
+package auth
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the access token payload
+type Claims struct {
+	UserID      string   `json:"userId"`
+	UserType    string   `json:"userType"`
+	PhoneNumber string   `json:"phoneNumber"`
+	SessionID   string   `json:"sid,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Actor       *Actor   `json:"act,omitempty"`
+	// AuthMethods is the amr claim; "mfa" means the session passed a second
+	// factor after the login OTP
+	AuthMethods []string `json:"amr,omitempty"`
+	// Persona is the app the session belongs to, "client" or "driver". It
+	// differs from UserType for users registered in both apps.
+	Persona string `json:"persona,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor names the staff member behind an impersonation token
+type Actor struct {
+	UserID string `json:"sub"`
+}
+
+// SessionPersona returns the app the token was issued for. Tokens minted
+// before auth-service added the persona claim fall back to DefaultPersona.
+func (c *Claims) SessionPersona() string {
+	if c.Persona != "" {
+		return c.Persona
+	}
+	return DefaultPersona(c.UserType)
+}
+
+// DefaultPersona is the persona of a session whose client did not ask for
+// one. Driver-app builds predating personas send none, so accounts that can
+// drive get the driver persona.
+func DefaultPersona(userType string) string {
+	if userType == "driver" || userType == "both" {
+		return "driver"
+	}
+	return "client"
+}
+
+// ParseToken verifies tokenString with keyFunc and returns its claims. Only
+// the algorithms auth-service signs with are accepted.
+func ParseToken(tokenString string, keyFunc jwt.Keyfunc) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc,
+		jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
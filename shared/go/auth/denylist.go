@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys auth-service writes and every service's middleware reads
+const (
+	tokenKeyPrefix   = "auth:denylist:jti:"
+	sessionKeyPrefix = "auth:denylist:sid:"
+	userKeyPrefix    = "auth:denylist:user:"
+)
+
+// FailOpen and FailClosed control what Check reports when Redis is unreachable
+const (
+	FailOpen   = "open"
+	FailClosed = "closed"
+)
+
+// checkTimeout bounds the Redis round trip on every authenticated request
+const checkTimeout = 250 * time.Millisecond
+
+// Denylist records revoked access tokens in Redis so they stop working before
+// they expire. Entries for sessions and users live as long as the longest
+// lived access token, after which every token they could cover has expired
+// anyway.
+type Denylist struct {
+	client   *redis.Client
+	failMode string
+	tokenTTL time.Duration
+}
+
+// NewDenylist creates a denylist. tokenTTL is the longest lifetime of any
+// access token the service issues, impersonation tokens included, and only
+// matters to services that revoke tokens; readers may pass 0.
+func NewDenylist(client *redis.Client, failMode string, tokenTTL time.Duration) *Denylist {
+	return &Denylist{
+		client:   client,
+		failMode: failMode,
+		tokenTTL: tokenTTL,
+	}
+}
+
+// RevokeToken denylists a single access token by its jti until it expires
+func (d *Denylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return d.client.Set(ctx, tokenKeyPrefix+jti, 1, ttl).Err()
+}
+
+// RevokeSessions denylists every access token issued for the given sessions
+// up to now. Like RevokeUser it works by issue time, so a session that stays
+// open (e.g. after a persona switch) can be given new tokens dated after it.
+func (d *Denylist) RevokeSessions(ctx context.Context, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	pipe := d.client.Pipeline()
+	for _, id := range sessionIDs {
+		pipe.Set(ctx, sessionKeyPrefix+id, now, d.tokenTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RevokeUser denylists every access token issued to a user up to now. iat
+// has second precision, so the whole current second is revoked; the issuer
+// must date new tokens after it (see RevokedAt).
+func (d *Denylist) RevokeUser(ctx context.Context, userID string) error {
+	return d.client.Set(ctx, userKeyPrefix+userID, time.Now().Unix(), d.tokenTTL).Err()
+}
+
+// RevokedAt returns the second of the latest RevokeUser or RevokeSessions
+// covering a new token for the user and session, or the zero time if there
+// is none. Tokens must be issued strictly after it to pass Check.
+func (d *Denylist) RevokedAt(ctx context.Context, userID, sessionID string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	keys := []string{userKeyPrefix + userID}
+	if sessionID != "" {
+		keys = append(keys, sessionKeyPrefix+sessionID)
+	}
+	values, err := d.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest int64
+	for _, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if at, err := strconv.ParseInt(raw, 10, 64); err == nil && at > latest {
+			latest = at
+		}
+	}
+	if latest == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(latest, 0), nil
+}
+
+// Check reports whether a token is revoked by jti, session or user. When
+// Redis cannot be reached the result follows the fail mode: open accepts the
+// token, closed rejects it. The error is returned either way so callers can
+// log or surface it.
+func (d *Denylist) Check(ctx context.Context, claims *Claims) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	keys := []string{tokenKeyPrefix + claims.ID, sessionKeyPrefix + claims.SessionID, userKeyPrefix + claims.UserID}
+	values, err := d.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return d.failMode == FailClosed, fmt.Errorf("denylist lookup failed: %w", err)
+	}
+
+	if claims.ID != "" && values[0] != nil {
+		return true, nil
+	}
+	if claims.SessionID != "" && values[1] != nil && !issuedAfter(claims, values[1]) {
+		return true, nil
+	}
+	if values[2] != nil && !issuedAfter(claims, values[2]) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// issuedAfter reports whether the token was issued strictly after the
+// revocation second stored in value. Tokens without iat never are.
+func issuedAfter(claims *Claims, value interface{}) bool {
+	raw, _ := value.(string)
+	revokedAt, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || claims.IssuedAt == nil {
+		return false
+	}
+	return claims.IssuedAt.Unix() > revokedAt
+}
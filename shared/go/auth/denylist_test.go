@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestDenylist(t *testing.T, failMode string) (*Denylist, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewDenylist(client, failMode, time.Hour), server
+}
+
+func testClaims(issuedAt time.Time) *Claims {
+	return &Claims{
+		UserID:    "user-1",
+		SessionID: "session-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       "jti-1",
+			IssuedAt: jwt.NewNumericDate(issuedAt),
+		},
+	}
+}
+
+func TestDenylistRevokeTokenAndSessions(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDenylist(t, FailClosed)
+	claims := testClaims(time.Now())
+
+	if revoked, err := d.Check(ctx, claims); err != nil || revoked {
+		t.Fatalf("fresh token: revoked = %v, err = %v", revoked, err)
+	}
+
+	if err := d.RevokeToken(ctx, "jti-1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := d.Check(ctx, claims); !revoked {
+		t.Error("token not revoked by jti")
+	}
+
+	other := testClaims(time.Now())
+	other.ID = "jti-2"
+	if err := d.RevokeSessions(ctx, "session-1"); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := d.Check(ctx, other); !revoked {
+		t.Error("token not revoked by session")
+	}
+
+	// A token the session is issued afterwards is dated after RevokedAt
+	revokedAt, err := d.RevokedAt(ctx, "user-1", "session-1")
+	if err != nil || revokedAt.IsZero() {
+		t.Fatalf("RevokedAt = %v, %v", revokedAt, err)
+	}
+	next := testClaims(revokedAt.Add(time.Second))
+	next.ID = "jti-3"
+	if revoked, err := d.Check(ctx, next); err != nil || revoked {
+		t.Errorf("token issued after the session revocation: revoked = %v, err = %v", revoked, err)
+	}
+}
+
+func TestDenylistRevokeTokenIgnoresExpired(t *testing.T) {
+	d, server := newTestDenylist(t, FailClosed)
+	if err := d.RevokeToken(context.Background(), "jti-1", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Errorf("keys %v written for an expired token", keys)
+	}
+}
+
+func TestDenylistRevokeUser(t *testing.T) {
+	ctx := context.Background()
+	d, _ := newTestDenylist(t, FailClosed)
+
+	if at, err := d.RevokedAt(ctx, "user-1", ""); err != nil || !at.IsZero() {
+		t.Fatalf("RevokedAt before revocation = %v, %v", at, err)
+	}
+	if err := d.RevokeUser(ctx, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	revokedAt, err := d.RevokedAt(ctx, "user-1", "")
+	if err != nil || revokedAt.IsZero() {
+		t.Fatalf("RevokedAt = %v, %v", revokedAt, err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"earlier second", revokedAt.Add(-time.Second), true},
+		{"same second", revokedAt, true},
+		{"next second", revokedAt.Add(time.Second), false},
+	} {
+		claims := testClaims(tc.issuedAt)
+		claims.ID, claims.SessionID = "", ""
+		if revoked, err := d.Check(ctx, claims); err != nil || revoked != tc.want {
+			t.Errorf("%s: revoked = %v, err = %v, want %v", tc.name, revoked, err, tc.want)
+		}
+	}
+}
+
+func TestDenylistEntriesLiveForTokenTTL(t *testing.T) {
+	ctx := context.Background()
+	d, server := newTestDenylist(t, FailClosed)
+
+	if err := d.RevokeUser(ctx, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RevokeSessions(ctx, "session-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{userKeyPrefix + "user-1", sessionKeyPrefix + "session-1"} {
+		if ttl := server.TTL(key); ttl != time.Hour {
+			t.Errorf("%s TTL = %v, want the token TTL of 1h", key, ttl)
+		}
+	}
+
+	server.FastForward(time.Hour)
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Errorf("keys %v outlived the token TTL", keys)
+	}
+}
+
+func TestDenylistFailMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		want bool
+	}{
+		{FailOpen, false},
+		{FailClosed, true},
+	} {
+		d, server := newTestDenylist(t, tc.mode)
+		server.Close()
+
+		revoked, err := d.Check(context.Background(), testClaims(time.Now()))
+		if err == nil {
+			t.Errorf("%s: no error with Redis down", tc.mode)
+		}
+		if revoked != tc.want {
+			t.Errorf("%s: revoked = %v, want %v", tc.mode, revoked, tc.want)
+		}
+	}
+}
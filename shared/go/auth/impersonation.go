@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// AuditImpersonation records every request made with an impersonation token
+// in impersonation_audit. It does its work after the handler returns, so it
+// can be installed on the router ahead of Middleware.
+func AuditImpersonation(db *pgxpool.Pool, service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		actorID := c.GetString("actorId")
+		if actorID == "" {
+			return
+		}
+
+		_, err := db.Exec(tracing.Context(c),
+			`INSERT INTO impersonation_audit
+			  (actor_id, target_user_id, token_id, event, service, method, path, status_code, ip_address)
+			 VALUES ($1, $2, $3, 'used', $4, $5, $6, $7, $8)`,
+			actorID, c.GetString("userId"), c.GetString("tokenId"), service,
+			c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP(),
+		)
+		if err != nil {
+			logging.Errorf(tracing.Context(c), "Error writing impersonation audit: %v", err)
+		}
+	}
+}
+
+// BlockImpersonation rejects requests made with an impersonation token. Use it
+// on routes staff must not reach while acting as a user.
+func BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("actorId") != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Error(
+				"IMPERSONATION_NOT_ALLOWED",
+				"This action is not available while impersonating a user",
+				nil,
+			))
+			return
+		}
+		c.Next()
+	}
+}
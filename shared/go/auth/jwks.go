@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/margwa/shared/go/tracing"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksMinRefresh stops tokens with unknown kids from hammering
+	// auth-service, and spaces out retries while it is failing
+	jwksMinRefresh = 30 * time.Second
+	// jwksFetchTimeout bounds one fetch and how long a request waits for it
+	jwksFetchTimeout = 5 * time.Second
+)
+
+// JWKSCache fetches and caches auth-service's public signing keys
+type JWKSCache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+	group  singleflight.Group
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time // last successful fetch
+	attemptedAt time.Time // last fetch, successful or not
+}
+
+func NewJWKSCache(url string, ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: jwksFetchTimeout, Transport: tracing.Transport()},
+		keys:   map[string]interface{}{},
+	}
+}
+
+// Key returns the public key for kid. A known key is returned at once, even
+// when the cache is stale or auth-service is down; the refresh runs in the
+// background. An unknown kid (a newly rotated key) waits for a refresh until
+// ctx is done.
+func (j *JWKSCache) Key(ctx context.Context, kid string) (interface{}, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) >= j.ttl
+	throttled := time.Since(j.attemptedAt) < jwksMinRefresh
+	j.mu.RUnlock()
+
+	if ok {
+		if stale && !throttled {
+			j.group.DoChan("jwks", j.refresh)
+		}
+		return key, nil
+	}
+	if throttled {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	select {
+	case res := <-j.group.DoChan("jwks", j.refresh):
+		if res.Err != nil {
+			return nil, res.Err
+		}
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for JWKS: %w", ctx.Err())
+	}
+
+	j.mu.RLock()
+	key, ok = j.keys[kid]
+	j.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// refresh replaces the cached keys with a fresh fetch. It runs under the
+// singleflight group, detached from any one request, and keeps the last good
+// key set when the fetch fails.
+func (j *JWKSCache) refresh() (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	keys, err := j.fetch(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.attemptedAt = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	j.keys = keys
+	j.fetchedAt = j.attemptedAt
+	return nil, nil
+}
+
+func (j *JWKSCache) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			keys[k.Kid] = ed25519.PublicKey(x)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS document has no usable keys")
+	}
+	return keys, nil
+}
+
+// Keyfunc verifies RS256/EdDSA tokens against the JWKS and, when hmacSecret
+// is not empty, HS256 tokens against the shared secret
+func Keyfunc(hmacSecret string, jwks *JWKSCache) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if hmacSecret == "" {
+				return nil, fmt.Errorf("HS256 tokens are not accepted")
+			}
+			return []byte(hmacSecret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodEd25519:
+			if jwks == nil {
+				return nil, fmt.Errorf("JWKS_URL is not configured")
+			}
+			kid, _ := token.Header["kid"].(string)
+			ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+			defer cancel()
+			return jwks.Key(ctx, kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer serves an Ed25519 JWKS whose keys and status the test controls
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]ed25519.PublicKey
+	status   int
+	requests int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: map[string]ed25519.PublicKey{}, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		var doc struct {
+			Keys []map[string]string `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			doc.Keys = append(doc.Keys, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": kid,
+				"x":   base64.RawURLEncoding.EncodeToString(key),
+			})
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKey(t *testing.T, kid string) ed25519.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = map[string]ed25519.PublicKey{kid: pub}
+	return pub
+}
+
+func (s *jwksServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// expire makes the cache stale and lets it refresh again at once
+func expire(j *JWKSCache) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fetchedAt = j.fetchedAt.Add(-2 * j.ttl)
+	j.attemptedAt = j.attemptedAt.Add(-2 * jwksMinRefresh)
+}
+
+// waitForRefresh returns once any background refresh has finished; Do joins
+// the call in flight, if there is one
+func waitForRefresh(j *JWKSCache) {
+	j.group.Do("jwks", func() (interface{}, error) { return nil, nil })
+}
+
+func TestJWKSCacheFetchesOnce(t *testing.T) {
+	ctx := context.Background()
+	server := newJWKSServer(t)
+	pub := server.setKey(t, "key-1")
+	j := NewJWKSCache(server.URL, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		key, err := j.Key(ctx, "key-1")
+		if err != nil {
+			t.Fatalf("Key: %v", err)
+		}
+		if !pub.Equal(key) {
+			t.Fatalf("Key returned %v, want the served key", key)
+		}
+	}
+	if n := server.requestCount(); n != 1 {
+		t.Errorf("%d JWKS requests, want 1", n)
+	}
+
+	// An unknown kid right after a fetch does not hit auth-service again
+	if _, err := j.Key(ctx, "key-2"); err == nil {
+		t.Error("unknown kid accepted")
+	}
+	if n := server.requestCount(); n != 1 {
+		t.Errorf("%d JWKS requests after an unknown kid, want 1", n)
+	}
+}
+
+func TestJWKSCacheRefreshesForNewKid(t *testing.T) {
+	ctx := context.Background()
+	server := newJWKSServer(t)
+	server.setKey(t, "key-1")
+	j := NewJWKSCache(server.URL, time.Hour)
+
+	if _, err := j.Key(ctx, "key-1"); err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+
+	rotated := server.setKey(t, "key-2")
+	expire(j)
+	key, err := j.Key(ctx, "key-2")
+	if err != nil {
+		t.Fatalf("Key after rotation: %v", err)
+	}
+	if !rotated.Equal(key) {
+		t.Error("Key did not return the rotated key")
+	}
+}
+
+func TestJWKSCacheKeepsLastGoodKeys(t *testing.T) {
+	ctx := context.Background()
+	server := newJWKSServer(t)
+	pub := server.setKey(t, "key-1")
+	j := NewJWKSCache(server.URL, time.Hour)
+
+	if _, err := j.Key(ctx, "key-1"); err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+
+	server.setStatus(http.StatusInternalServerError)
+	expire(j)
+
+	// A stale known key is served while the refresh fails in the background
+	key, err := j.Key(ctx, "key-1")
+	if err != nil || !pub.Equal(key) {
+		t.Fatalf("stale Key = %v, %v", key, err)
+	}
+	waitForRefresh(j)
+	if n := server.requestCount(); n != 2 {
+		t.Fatalf("%d JWKS requests, want 2", n)
+	}
+
+	key, err = j.Key(ctx, "key-1")
+	if err != nil || !pub.Equal(key) {
+		t.Errorf("Key after a failed refresh = %v, %v", key, err)
+	}
+
+	// An unknown kid surfaces the failure
+	expire(j)
+	if _, err := j.Key(ctx, "key-2"); err == nil {
+		t.Error("unknown kid accepted while the JWKS endpoint is failing")
+	}
+	if key, err := j.Key(ctx, "key-1"); err != nil || !pub.Equal(key) {
+		t.Errorf("Key after a failed fetch for an unknown kid = %v, %v", key, err)
+	}
+}
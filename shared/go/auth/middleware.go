@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/margwa/shared/go/logging"
+	"github.com/margwa/shared/go/response"
+)
+
+// Middleware verifies the bearer token with keyFunc (see Keyfunc) and, when
+// revoked is not nil, rejects tokens on the denylist. It stores the claims in
+// the gin context under userId, userType, phoneNumber, sessionId, persona,
+// roles, permissions, tokenId, tokenExpiresAt, amr and, for impersonation
+// tokens, actorId.
+func Middleware(keyFunc jwt.Keyfunc, revoked *Denylist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Error(
+				"UNAUTHORIZED",
+				"No authentication token provided",
+				nil,
+			))
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Error(
+				"UNAUTHORIZED",
+				"Invalid authentication token format",
+				nil,
+			))
+			return
+		}
+
+		claims, err := ParseToken(parts[1], keyFunc)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Error(
+				"TOKEN_EXPIRED",
+				"Authentication token expired or invalid",
+				nil,
+			))
+			return
+		}
+
+		if revoked != nil {
+			isRevoked, err := revoked.Check(c.Request.Context(), claims)
+			if err != nil {
+				logging.Warnf(c.Request.Context(), "Token denylist unavailable: %v", err)
+				if isRevoked {
+					c.AbortWithStatusJSON(http.StatusServiceUnavailable, response.Error(
+						"AUTH_UNAVAILABLE",
+						"Unable to verify authentication token, please retry",
+						nil,
+					))
+					return
+				}
+			} else if isRevoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response.Error(
+					"TOKEN_REVOKED",
+					"Authentication token has been revoked",
+					nil,
+				))
+				return
+			}
+		}
+
+		c.Set("userId", claims.UserID)
+		c.Set("userType", claims.UserType)
+		c.Set("phoneNumber", claims.PhoneNumber)
+		c.Set("sessionId", claims.SessionID)
+		c.Set("persona", claims.SessionPersona())
+		c.Set("roles", claims.Roles)
+		c.Set("permissions", claims.Permissions)
+		c.Set("tokenId", claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+		}
+		c.Set("amr", claims.AuthMethods)
+		if claims.Actor != nil {
+			c.Set("actorId", claims.Actor.UserID)
+		}
+		c.Next()
+	}
+}
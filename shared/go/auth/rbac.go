@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/margwa/shared/go/response"
+)
+
+// RequireRole allows the request if the access token carries any of roles.
+// It must run after Middleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !containsAny(c.GetStringSlice("roles"), roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Error(
+				"FORBIDDEN",
+				"You do not have the role required for this action",
+				gin.H{"requiredRoles": roles},
+			))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission allows the request only if the access token carries every
+// one of permissions. It must run after Middleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !containsAll(c.GetStringSlice("permissions"), permissions) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Error(
+				"FORBIDDEN",
+				"You do not have permission for this action",
+				gin.H{"requiredPermissions": permissions},
+			))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePersona only accepts sessions acting as persona, so a user
+// registered in both apps cannot use their rider session in the driver app.
+// It must run after Middleware.
+func RequirePersona(persona string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if current := c.GetString("persona"); current != persona {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Error(
+				"PERSONA_MISMATCH",
+				"This session is not signed in to the "+persona+" app",
+				gin.H{"persona": current, "switchPath": "/auth/switch-persona"},
+			))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireMFA rejects users of the given types unless their access token's
+// amr claim shows the session passed a second factor (TOTP or a recovery
+// code) in auth-service. Other user types pass through. It must run after
+// Middleware.
+func RequireMFA(userTypes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if containsAny([]string{c.GetString("userType")}, userTypes) &&
+			!containsAny(c.GetStringSlice("amr"), []string{"mfa"}) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Error(
+				"MFA_REQUIRED",
+				"Verify with your authenticator app before continuing",
+				gin.H{"verifyPath": "/auth/mfa/verify"},
+			))
+			return
+		}
+		c.Next()
+	}
+}
+
+func containsAny(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !containsAny(have, []string{w}) {
+			return false
+		}
+	}
+	return true
+}
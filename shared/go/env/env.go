@@ -0,0 +1,32 @@
+// Package env reads service configuration from environment variables.
+package env
+
+import (
+	"log"
+
+	"github.com/joho/godotenv"
+)
+
+// Load reads each of files that exists into the environment. Variables
+// already set, including those from an earlier file, are not overwritten, so
+// deployments can override a checked-out .env.
+func Load(files ...string) {
+	found := false
+	for _, file := range files {
+		if err := godotenv.Load(file); err == nil {
+			found = true
+		}
+	}
+	if !found {
+		log.Println("No .env file found, using system environment variables")
+	}
+}
+
+// Get returns the value of key, or of the file named by key_FILE, or
+// defaultValue when neither is set
+func Get(key, defaultValue string) string {
+	if value, ok, err := lookup(key); err == nil && ok {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,258 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse fills the exported fields of the struct cfg points to from the
+// environment. Fields are described with tags:
+//
+//	Port      string        `env:"AUTH_SERVICE_PORT" default:"3001"`
+//	JWTSecret string        `env:"JWT_SECRET" required:"true" secret:"true"`
+//	TokenTTL  time.Duration `env:"JWT_EXPIRES_IN" default:"15m"`
+//	FailMode  string        `env:"TOKEN_DENYLIST_FAIL_MODE" default:"open" oneof:"open,closed"`
+//
+// Any variable can instead be read from a file named by the same variable
+// with a _FILE suffix (JWT_SECRET_FILE=/run/secrets/jwt), which is how
+// Kubernetes and Docker mount secrets. Supported field types are string,
+// bool, int, float64, time.Duration and comma-separated []string. Every
+// invalid or missing value is reported in the returned error, not just the
+// first.
+func Parse(cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env: Parse needs a pointer to a struct, got %T", cfg)
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		raw, ok, err := lookup(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			if field.Tag.Get("required") == "true" {
+				errs = append(errs, fmt.Errorf("%s is required", name))
+				continue
+			}
+			raw = field.Tag.Get("default")
+		}
+
+		if options := field.Tag.Get("oneof"); options != "" && !contains(strings.Split(options, ","), raw) {
+			errs = append(errs, fmt.Errorf("%s must be one of %s, got %q", name, options, raw))
+			continue
+		}
+		if err := set(v.Field(i), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultSecrets returns the variables of fields tagged secret:"true" that
+// still hold their built-in default. Services refuse to start in production
+// while any are left.
+func DefaultSecrets(cfg interface{}) []string {
+	v := reflect.Indirect(reflect.ValueOf(cfg))
+	t := v.Type()
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		def := field.Tag.Get("default")
+		if name == "" || def == "" || field.Tag.Get("secret") != "true" {
+			continue
+		}
+		if format(v.Field(i)) == format(defaultValue(field.Type, def)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Print writes the effective configuration as NAME=value lines, in field
+// order. Values of fields tagged secret:"true" are replaced with [redacted].
+func Print(w io.Writer, cfg interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(cfg))
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		value := format(v.Field(i))
+		if field.Tag.Get("secret") == "true" && value != "" {
+			value = "[redacted]"
+		}
+		if os.Getenv(name+"_FILE") != "" {
+			value += " (from " + name + "_FILE)"
+		}
+		fmt.Fprintf(w, "%s=%s\n", name, value)
+	}
+}
+
+// lookup reads name from the environment, or from the file named by
+// name_FILE. A variable set to the empty string counts as unset, as it
+// always has for Get.
+func lookup(name string) (string, bool, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+	if value := os.Getenv(name); value != "" {
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func set(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		if raw == "" {
+			field.SetInt(0)
+			return nil
+		}
+		d, err := ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			field.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if raw == "" {
+			field.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		if raw == "" {
+			field.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+func defaultValue(t reflect.Type, raw string) reflect.Value {
+	v := reflect.New(t).Elem()
+	set(v, raw)
+	return v
+}
+
+func format(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return FormatDuration(time.Duration(v.Int()))
+	case v.Kind() == reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// ParseDuration extends time.ParseDuration with a "d" suffix for whole days,
+// so values like JWT_REFRESH_EXPIRES_IN=30d parse
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration is the inverse of ParseDuration, using the largest whole
+// unit: 30d, 12h, 15m, 1m30s
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0s"
+	case d%(24*time.Hour) == 0:
+		return strconv.FormatInt(int64(d/(24*time.Hour)), 10) + "d"
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	default:
+		return d.String()
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Port     string        `env:"TEST_PORT" default:"3001"`
+	Secret   string        `env:"TEST_SECRET" required:"true" secret:"true"`
+	Signing  string        `env:"TEST_SIGNING_SECRET" default:"change-me" secret:"true"`
+	TTL      time.Duration `env:"TEST_TTL" default:"15m"`
+	FailMode string        `env:"TEST_FAIL_MODE" default:"open" oneof:"open,closed"`
+	Debug    bool          `env:"TEST_DEBUG"`
+	Hosts    []string      `env:"TEST_HOSTS" default:"a, b,,c"`
+}
+
+func TestParseDefaults(t *testing.T) {
+	t.Setenv("TEST_SECRET", "s3cret")
+
+	var cfg testConfig
+	if err := Parse(&cfg); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Port != "3001" || cfg.TTL != 15*time.Minute || cfg.FailMode != "open" || cfg.Debug {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+	if strings.Join(cfg.Hosts, "|") != "a|b|c" {
+		t.Errorf("Hosts = %q, want [a b c]", cfg.Hosts)
+	}
+}
+
+func TestParseRequired(t *testing.T) {
+	var cfg testConfig
+	err := Parse(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "TEST_SECRET is required") {
+		t.Fatalf("Parse error = %v, want TEST_SECRET is required", err)
+	}
+
+	// An empty value counts as unset
+	t.Setenv("TEST_SECRET", "")
+	if err := Parse(&cfg); err == nil {
+		t.Error("empty TEST_SECRET accepted")
+	}
+}
+
+func TestParseReportsEveryError(t *testing.T) {
+	t.Setenv("TEST_TTL", "soon")
+	t.Setenv("TEST_FAIL_MODE", "sideways")
+	t.Setenv("TEST_DEBUG", "maybe")
+
+	var cfg testConfig
+	err := Parse(&cfg)
+	if err == nil {
+		t.Fatal("Parse accepted invalid values")
+	}
+	for _, want := range []string{
+		"TEST_SECRET is required",
+		`TEST_TTL: invalid duration "soon"`,
+		`TEST_FAIL_MODE must be one of open,closed, got "sideways"`,
+		`TEST_DEBUG: invalid boolean "maybe"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestParseOneOf(t *testing.T) {
+	t.Setenv("TEST_SECRET", "s3cret")
+	t.Setenv("TEST_FAIL_MODE", "closed")
+
+	var cfg testConfig
+	if err := Parse(&cfg); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.FailMode != "closed" {
+		t.Errorf("FailMode = %q, want closed", cfg.FailMode)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET", "from-env")
+	t.Setenv("TEST_SECRET_FILE", path)
+	t.Setenv("TEST_TTL", "30d")
+
+	var cfg testConfig
+	if err := Parse(&cfg); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Secret != "from-file" {
+		t.Errorf("Secret = %q, want the file's contents without the newline", cfg.Secret)
+	}
+	if cfg.TTL != 30*24*time.Hour {
+		t.Errorf("TTL = %v, want 30d", cfg.TTL)
+	}
+
+	t.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	if err := Parse(&cfg); err == nil || !strings.Contains(err.Error(), "TEST_SECRET_FILE") {
+		t.Errorf("Parse error = %v, want it to name TEST_SECRET_FILE", err)
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	t.Setenv("TEST_SECRET", "s3cret")
+
+	var cfg testConfig
+	if err := Parse(&cfg); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if names := DefaultSecrets(&cfg); len(names) != 1 || names[0] != "TEST_SIGNING_SECRET" {
+		t.Errorf("DefaultSecrets = %v, want [TEST_SIGNING_SECRET]", names)
+	}
+
+	var out strings.Builder
+	Print(&out, &cfg)
+	if strings.Contains(out.String(), "s3cret") || strings.Contains(out.String(), "change-me") {
+		t.Errorf("Print leaked a secret:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "TEST_SECRET=[redacted]\n") || !strings.Contains(out.String(), "TEST_TTL=15m\n") {
+		t.Errorf("unexpected Print output:\n%s", out.String())
+	}
+}
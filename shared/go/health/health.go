@@ -0,0 +1,151 @@
+// Package health serves the liveness and readiness probes of the Go
+// services. /livez only says the process is up; /readyz checks every
+// dependency the service needs to handle requests and fails while the
+// service is draining for shutdown.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// CheckFunc reports whether a dependency is usable. It must return once ctx
+// is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Checker holds a service's readiness checks and its draining flag
+type Checker struct {
+	service  string
+	checks   []check
+	draining atomic.Bool
+}
+
+func New(service string) *Checker {
+	return &Checker{service: service}
+}
+
+// Add registers a readiness check. Each check runs with its own timeout,
+// concurrently with the others.
+func (h *Checker) Add(name string, timeout time.Duration, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Drain makes readiness fail from now on, so load balancers stop sending
+// traffic before the server stops accepting connections
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called
+func (h *Checker) Draining() bool {
+	return h.draining.Load()
+}
+
+// Live answers /livez. It never touches dependencies: an unreachable
+// database should take the pod out of rotation, not restart it.
+func (h *Checker) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ok",
+		"service":   h.service,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// Ready answers /readyz with 200 when every check passes and 503 otherwise,
+// listing each dependency's status and latency
+func (h *Checker) Ready(c *gin.Context) {
+	type result struct {
+		Status    string  `json:"status"`
+		LatencyMs float64 `json:"latencyMs"`
+		Error     string  `json:"error,omitempty"`
+	}
+
+	results := make(map[string]result, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range h.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), chk.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.fn(ctx)
+			r := result{Status: "up", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				r.Status = "down"
+				r.Error = err.Error()
+			}
+			mu.Lock()
+			results[chk.name] = r
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	for _, r := range results {
+		if r.Status != "up" {
+			status, code = "not_ready", http.StatusServiceUnavailable
+		}
+	}
+	if h.Draining() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":    status,
+		"service":   h.service,
+		"checks":    results,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// Postgres checks that a pool connection can be acquired and pinged
+func Postgres(pool *pgxpool.Pool) CheckFunc {
+	return func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	}
+}
+
+// Redis checks that the server answers PING
+func Redis(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// HTTP checks that url answers a GET with a 2xx status, for services this
+// one calls synchronously
+func HTTP(url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+// Keys whose values are never logged, compared lowercased with _ and -
+// removed so phoneNumber, phone_number and Phone-Number all match
+var sensitiveKeys = map[string]bool{
+	"phone":         true,
+	"phonenumber":   true,
+	"to":            true,
+	"otp":           true,
+	"otpcode":       true,
+	"code":          true,
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"documenturl":   true,
+	"imageurl":      true,
+}
+
+var (
+	// JSON web tokens and bearer credentials
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	// One-time codes next to the words that introduce them ("code is 123456", "otp=1234")
+	otpPattern = regexp.MustCompile(`(?i)((?:otp|code|passcode)[^0-9\n]{0,20}?)\b\d{4,8}\b`)
+	// Phone numbers: E.164, or Indian mobile numbers written without the +.
+	// Other bare digit runs, such as Unix timestamps in seconds or
+	// milliseconds, are left alone.
+	phonePattern = regexp.MustCompile(`\+\d{10,15}\b|\b(?:0|91)?[6-9]\d{9}\b`)
+	// URLs of uploaded documents, or carrying a storage signature
+	documentURLPattern = regexp.MustCompile(`(?i)(https?://[^/\s"']+)/[^\s"']*(?:document|X-Amz-Signature)[^\s"']*`)
+)
+
+// Redact masks phone numbers, OTPs, tokens and document URLs in free text.
+// Phone numbers keep their last two digits so lines about the same number
+// can still be matched up.
+func Redact(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = documentURLPattern.ReplaceAllString(s, "${1}/"+redacted)
+	s = otpPattern.ReplaceAllString(s, "${1}"+redacted)
+	return maskPhones(s)
+}
+
+// maskPhones masks phone numbers, skipping digit runs that end a UUID or
+// other hyphenated identifier
+func maskPhones(s string) string {
+	matches := phonePattern.FindAllStringIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if start > 0 && s[start-1] == '-' {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(strings.Repeat("*", end-start-2))
+		b.WriteString(s[end-2 : end])
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	key := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(a.Key))
+	if sensitiveKeys[key] {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, Redact(a.Value.String()))
+	}
+	if a.Value.Kind() == slog.KindAny {
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{"E.164 number", "OTP sent to +919876543210", "OTP sent to ***********10"},
+		{"bare mobile number", "lookup 9876543210 failed", "lookup ********10 failed"},
+		{"mobile number with trunk prefix", "lookup 09876543210 failed", "lookup *********10 failed"},
+		{"mobile number with country code", "lookup 919876543210 failed", "lookup **********10 failed"},
+		{"unix timestamp", "token iat 1760000000 exp 1760000900", "token iat 1760000000 exp 1760000900"},
+		{"unix timestamp in milliseconds", "sentAt=1760000000123", "sentAt=1760000000123"},
+		{"UUID suffix", "driver 7f1c2a4e-1b2c-4d5e-8f90-987654321012", "driver 7f1c2a4e-1b2c-4d5e-8f90-987654321012"},
+		{"otp", "Your code is 123456", "Your code is [redacted]"},
+		{"otp assignment", "otp=4821 accepted", "otp=[redacted] accepted"},
+		{"jwt", "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln rejected", "token [redacted] rejected"},
+		{"bearer", "Authorization: Bearer abc.def-ghi", "Authorization: Bearer [redacted]"},
+		{"document url", "uploaded https://cdn.margwa.app/documents/license.png", "uploaded https://cdn.margwa.app/[redacted]"},
+		{"signed url", "GET https://s3.example.com/bucket/a.png?X-Amz-Signature=abc", "GET https://s3.example.com/[redacted]"},
+		{"plain text", "Booking 42 accepted", "Booking 42 accepted"},
+	} {
+		if got := Redact(tc.in); got != tc.want {
+			t.Errorf("%s: Redact(%q) = %q, want %q", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRedactAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redactAttr}))
+	logger.Info("login",
+		"phone_number", "+919876543210",
+		"otpCode", "123456",
+		"to", "+919876543210",
+		"detail", "sent to 9876543210",
+		"err", errors.New("code is 654321"),
+		"issued_at", int64(1760000000),
+		"user_id", "7f1c2a4e-1b2c-4d5e-8f90-987654321012",
+	)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decoding log line %q: %v", buf.String(), err)
+	}
+	for key, want := range map[string]interface{}{
+		"phone_number": redacted,
+		"otpCode":      redacted,
+		"to":           redacted,
+		"detail":       "sent to ********10",
+		"err":          "code is " + redacted,
+		"issued_at":    float64(1760000000),
+		"user_id":      "7f1c2a4e-1b2c-4d5e-8f90-987654321012",
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package metrics exposes Prometheus metrics for the Go services: HTTP
+// request metrics recorded by gin middleware, connection pool stats and the
+// /metrics endpoint. Services declare their own domain counters with promauto
+// and they are served alongside.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the HTTP collectors for one service. Every series carries a
+// service label so alert rules can group by it. Create one per process; the
+// collectors are registered with the default registry.
+type Metrics struct {
+	service  string
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+func New(service string) *Metrics {
+	labels := prometheus.Labels{"service": service}
+	m := &Metrics{
+		service: service,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "http_requests_total",
+			Help:        "HTTP requests handled, by route template and status.",
+			ConstLabels: labels,
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "http_request_duration_seconds",
+			Help:        "HTTP request latency, by route template and status.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "http_requests_in_flight",
+			Help:        "HTTP requests currently being handled.",
+			ConstLabels: labels,
+		}),
+	}
+	prometheus.MustRegister(m.requests, m.duration, m.inFlight)
+	return m
+}
+
+// Middleware records every request. Routes are labelled with their template
+// (/drivers/:id, not /drivers/42) to keep cardinality bounded; requests that
+// match no route share the "unmatched" label.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.duration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the default registry in the Prometheus text format,
+// including the Go runtime and process collectors
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
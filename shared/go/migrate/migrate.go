@@ -0,0 +1,357 @@
+// Package migrate applies the platform's versioned SQL migrations. The
+// files in sql/ are embedded in every binary that imports the package, so
+// a service always knows which schema it was built for.
+//
+// Base tables are still created by drizzle (shared/database); these
+// migrations add what the Go services need on top. Applied files are never
+// edited; fixes go in a new migration. Databases patched by hand before
+// schema_migrations existed are adopted with Baseline.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+const undefinedTable = "42P01"
+
+// lockKey is the advisory lock key held while migrating, so replicas
+// starting together apply each migration once
+const lockKey int64 = 0x6d61726777610001
+
+// lockPollInterval is how often a waiting migrator retries the lock
+const lockPollInterval = time.Second
+
+// LockWait bounds how long Up and Baseline wait for another instance's
+// migration run when their context has no deadline
+var LockWait = 5 * time.Minute
+
+// Migration is one NNNN_name.sql file
+type Migration struct {
+	Version  int64
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// Applied is a row of schema_migrations
+type Applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// All returns the embedded migrations in version order
+func All() ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Latest returns the version of the newest embedded migration
+func Latest() int64 {
+	migrations, err := All()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Run applies every pending embedded migration, logging each one
+func Run(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := All()
+	if err != nil {
+		return err
+	}
+	applied, err := Up(ctx, pool, migrations)
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+// Load reads NNNN_name.sql files from the root of fsys in version order.
+// Other files are ignored; duplicate versions are an error.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int64]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".sql" {
+			continue
+		}
+		prefix, rest, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_description.sql", name)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     rest,
+			SQL:      string(content),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every migration newer than the database's current version,
+// each in its own transaction together with its schema_migrations row. It
+// holds an advisory lock throughout, and refuses to run when an applied
+// migration's file has changed since it was applied.
+func Up(ctx context.Context, pool *pgxpool.Pool, migrations []Migration) ([]Migration, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	done, err := prepare(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	var ran []Migration
+	for _, m := range migrations {
+		if a, ok := done[m.Version]; ok {
+			if a.Checksum != m.Checksum {
+				return ran, fmt.Errorf("migration %04d_%s was changed after it was applied; add a new migration instead", m.Version, m.Name)
+			}
+			continue
+		}
+
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, m.SQL); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				m.Version, m.Name, m.Checksum,
+			)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Baseline records the migrations up to version as applied without running
+// them, for databases that had those SQL files applied by hand before
+// schema_migrations existed. Migrations already recorded are left alone.
+func Baseline(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, version int64) ([]Migration, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	done, err := prepare(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	var marked []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		if _, err := conn.Exec(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			m.Version, m.Name, m.Checksum,
+		); err != nil {
+			return marked, fmt.Errorf("recording migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		marked = append(marked, m)
+	}
+	return marked, nil
+}
+
+// prepare takes the migration lock on conn, creates schema_migrations when
+// needed and returns the applied migrations by version. The caller releases
+// the lock.
+func prepare(ctx context.Context, conn *pgxpool.Conn) (map[int64]Applied, error) {
+	if err := lock(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	_, err := conn.Exec(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+	)
+	var applied []Applied
+	if err == nil {
+		applied, err = list(ctx, conn.Conn())
+	} else {
+		err = fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	if err != nil {
+		conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+		return nil, err
+	}
+
+	done := make(map[int64]Applied, len(applied))
+	for _, a := range applied {
+		done[a.Version] = a
+	}
+	return done, nil
+}
+
+// lock polls pg_try_advisory_lock until it succeeds or the wait runs out,
+// so an instance stuck behind a hung migration fails instead of blocking
+// forever. The wait is ctx's deadline, or LockWait when ctx has none.
+func lock(ctx context.Context, conn *pgxpool.Conn) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, LockWait)
+		defer cancel()
+	}
+
+	for {
+		var locked bool
+		if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, lockKey).Scan(&locked); err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		if locked {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("another migration run still holds the lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Status lists the applied migrations, oldest first. It returns none when
+// migrations have never run.
+func Status(ctx context.Context, pool *pgxpool.Pool) ([]Applied, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	return list(ctx, conn.Conn())
+}
+
+// Version returns the newest applied migration, or 0 when migrations have
+// never run
+func Version(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	var version int64
+	err := pool.QueryRow(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`,
+	).Scan(&version)
+	if isUndefinedTable(err) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// Require fails unless every embedded migration up to version has been
+// applied from an identical file, so a service refuses to start against a
+// schema it would break on. Applied migrations newer than version must also
+// match, which catches a binary built from diverged SQL.
+func Require(ctx context.Context, pool *pgxpool.Pool, version int64) error {
+	migrations, err := All()
+	if err != nil {
+		return err
+	}
+	applied, err := Status(ctx, pool)
+	if err != nil {
+		return fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	done := make(map[int64]Applied, len(applied))
+	for _, a := range applied {
+		done[a.Version] = a
+	}
+
+	var missing []string
+	for _, m := range migrations {
+		a, ok := done[m.Version]
+		switch {
+		case ok && a.Checksum != m.Checksum:
+			return fmt.Errorf("migration %04d_%s differs from the one applied to the database", m.Version, m.Name)
+		case !ok && m.Version <= version:
+			missing = append(missing, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("migrations %s are required but not applied; run migrations first", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func list(ctx context.Context, conn *pgx.Conn) ([]Applied, error) {
+	rows, err := conn.Query(ctx,
+		`SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`,
+	)
+	var applied []Applied
+	if err == nil {
+		applied, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (Applied, error) {
+			var a Applied
+			err := row.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt)
+			return a, err
+		})
+	}
+	if isUndefinedTable(err) {
+		return nil, nil
+	}
+	return applied, err
+}
+
+// isUndefinedTable reports whether err means schema_migrations has not
+// been created yet
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTable
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.sql":      {Data: []byte("CREATE INDEX a ON t (a);")},
+		"0001_create_table.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+		"0010_backfill_names.sql": {Data: []byte("UPDATE t SET a = 1;")},
+		"README.md":               {Data: []byte("not a migration")},
+		"archive/0003_old.sql":    {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var got []string
+	for _, m := range migrations {
+		got = append(got, m.Name)
+		if len(m.Checksum) != 64 || m.SQL == "" {
+			t.Errorf("%s: SQL %q, checksum %q", m.Name, m.SQL, m.Checksum)
+		}
+	}
+	if want := "create_table,add_index,backfill_names"; strings.Join(got, ",") != want {
+		t.Errorf("Load returned %v, want %s in version order", got, want)
+	}
+	if migrations[2].Version != 10 {
+		t.Errorf("version = %d, want 10", migrations[2].Version)
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	for _, name := range []string{
+		"create_table.sql",
+		"0001-create_table.sql",
+		"0000_zero.sql",
+		"0001.sql",
+	} {
+		_, err := Load(fstest.MapFS{name: {Data: []byte("SELECT 1;")}})
+		if err == nil || !strings.Contains(err.Error(), "0001_description.sql") {
+			t.Errorf("%s: Load error = %v, want a naming error", name, err)
+		}
+	}
+}
+
+func TestLoadRejectsDuplicateVersions(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"0004_add_column.sql": {Data: []byte("SELECT 1;")},
+		"004_other.sql":       {Data: []byte("SELECT 2;")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "share version 4") {
+		t.Errorf("Load error = %v, want a duplicate version error", err)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(migrations) == 0 || Latest() != migrations[len(migrations)-1].Version {
+		t.Errorf("Latest() = %d with %d embedded migrations", Latest(), len(migrations))
+	}
+}
@@ -0,0 +1,5 @@
+-- Track SMS delivery outcome for OTP verifications
+
+ALTER TABLE otp_verifications
+ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(20) NOT NULL DEFAULT 'pending',
+ADD COLUMN IF NOT EXISTS delivery_error TEXT;
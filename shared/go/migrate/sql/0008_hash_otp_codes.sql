@@ -0,0 +1,12 @@
+-- Store OTP codes as HMAC-SHA256 digests instead of plaintext
+-- Digests are prefixed with 'hmac-sha256:' and need more than 6 characters
+
+ALTER TABLE otp_verifications
+ALTER COLUMN otp_code TYPE VARCHAR(128);
+
+-- Expire any pending plaintext codes; auth-service also rejects them at verify time
+UPDATE otp_verifications
+SET expires_at = NOW()
+WHERE verified_at IS NULL
+  AND otp_code NOT LIKE 'hmac-sha256:%'
+  AND expires_at > NOW();
@@ -0,0 +1,11 @@
+-- Account deletion with a grace period
+-- deletion_scheduled_at is set when the user asks to delete their account;
+-- auth-service anonymizes the row and sets deleted_at once it passes
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at
+ON users(deletion_scheduled_at)
+WHERE deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,63 @@
+-- Store phone numbers in E.164 form (e.g. +919876543210)
+-- auth-service normalizes numbers before storing or looking them up
+
+-- Country codes carry a leading "+" (e.g. "91" becomes "+91")
+UPDATE users
+SET phone_country_code = '+' || regexp_replace(phone_country_code, '[^0-9]', '', 'g'),
+    updated_at = NOW()
+WHERE phone_country_code NOT LIKE '+%'
+  AND regexp_replace(phone_country_code, '[^0-9]', '', 'g') <> '';
+
+CREATE TEMP TABLE phone_normalization ON COMMIT DROP AS
+SELECT id,
+       phone_number,
+       phone_country_code || ltrim(regexp_replace(phone_number, '[^0-9]', '', 'g'), '0') AS normalized
+FROM users
+WHERE phone_number NOT LIKE '+%'
+  AND phone_number NOT LIKE 'del\_%';
+
+-- Numbers that would collide with another account (e.g. "098765 43210" and
+-- "9876543210"), or not fit the column, are left as they are and listed here
+-- to be merged or fixed by hand
+CREATE TABLE IF NOT EXISTS phone_number_conflicts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    phone_number VARCHAR(20) NOT NULL,
+    normalized_phone_number TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO phone_number_conflicts (user_id, phone_number, normalized_phone_number)
+SELECT n.id, n.phone_number, n.normalized
+FROM phone_normalization n
+WHERE length(n.normalized) > 20
+   OR EXISTS (SELECT 1 FROM users u WHERE u.phone_number = n.normalized AND u.id <> n.id)
+   OR EXISTS (SELECT 1 FROM phone_normalization o WHERE o.normalized = n.normalized AND o.id <> n.id)
+ON CONFLICT (user_id) DO NOTHING;
+
+UPDATE users u
+SET phone_number = n.normalized,
+    updated_at = NOW()
+FROM phone_normalization n
+WHERE u.id = n.id
+  AND NOT EXISTS (SELECT 1 FROM phone_number_conflicts c WHERE c.user_id = n.id);
+
+UPDATE otp_verifications o
+SET phone_number = u.phone_number
+FROM users u
+WHERE o.user_id = u.id
+  AND o.phone_number NOT LIKE '+%'
+  AND u.phone_number LIKE '+%';
+
+DO $$
+DECLARE
+    conflicts INTEGER;
+BEGIN
+    SELECT count(*) INTO conflicts FROM phone_number_conflicts;
+    IF conflicts > 0 THEN
+        RAISE WARNING '% phone numbers could not be normalized; see phone_number_conflicts', conflicts;
+    END IF;
+END $$;
+
+-- Separate login codes from phone-change codes so one cannot be used for the other
+ALTER TABLE otp_verifications
+ADD COLUMN IF NOT EXISTS purpose VARCHAR(20) NOT NULL DEFAULT 'login';
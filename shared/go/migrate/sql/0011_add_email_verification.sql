@@ -0,0 +1,9 @@
+-- Email verification and email delivery of login OTPs
+-- email_verified_at is cleared whenever the email changes
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- How the code was delivered: 'sms' or 'email'
+ALTER TABLE otp_verifications
+ADD COLUMN IF NOT EXISTS delivery_channel VARCHAR(10) NOT NULL DEFAULT 'sms';
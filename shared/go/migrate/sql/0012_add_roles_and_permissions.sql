@@ -0,0 +1,69 @@
+-- Role-based access control
+-- Roles and their permissions are embedded in access tokens by auth-service;
+-- services check them with RequireRole / RequirePermission
+
+CREATE TABLE IF NOT EXISTS roles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id UUID NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+
+-- Seed roles
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to every admin operation'),
+    ('support', 'Customer support: user lookup, driver document review, session revocation'),
+    ('finance', 'Payments, refunds and financial reporting')
+ON CONFLICT (name) DO NOTHING;
+
+-- Seed permissions
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'View any user account'),
+    ('users:manage', 'Deactivate accounts and revoke their sessions'),
+    ('roles:manage', 'Grant and revoke roles'),
+    ('drivers:verify', 'Approve or reject driver documents'),
+    ('payments:read', 'View any payment'),
+    ('payments:refund', 'Issue refunds'),
+    ('analytics:read', 'View platform-wide analytics and reports')
+ON CONFLICT (name) DO NOTHING;
+
+-- admin gets every permission
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+  ON p.name IN ('users:read', 'users:manage', 'drivers:verify', 'payments:read')
+WHERE r.name = 'support'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p
+  ON p.name IN ('payments:read', 'payments:refund', 'analytics:read')
+WHERE r.name = 'finance'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,30 @@
+-- Admin impersonation
+-- Every impersonation token issued and every request made with one is recorded
+
+CREATE TABLE IF NOT EXISTS impersonation_audit (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID NOT NULL REFERENCES users(id),
+    target_user_id UUID NOT NULL REFERENCES users(id),
+    token_id VARCHAR(64) NOT NULL,
+    event VARCHAR(20) NOT NULL, -- 'issued' or 'used'
+    reason TEXT,
+    service VARCHAR(50) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_audit_actor_id ON impersonation_audit(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_impersonation_audit_target_user_id ON impersonation_audit(target_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_impersonation_audit_token_id ON impersonation_audit(token_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Act as a rider or driver to reproduce what they see')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'users:impersonate'
+WHERE r.name IN ('admin', 'support')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+-- Suspicious-login detection
+-- Each session keeps the risk decision made when it was created
+
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS risk_score INTEGER,
+ADD COLUMN IF NOT EXISTS risk_level VARCHAR(10),
+ADD COLUMN IF NOT EXISTS risk_reasons TEXT[],
+-- Second factor passed by a high-risk login, e.g. 'email'
+ADD COLUMN IF NOT EXISTS step_up_method VARCHAR(20);
+
+-- Login history lookups when scoring a new login
+CREATE INDEX IF NOT EXISTS idx_auth_events_user_type ON auth_events(user_id, event_type, created_at DESC);
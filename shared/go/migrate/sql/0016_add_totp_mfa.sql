@@ -0,0 +1,26 @@
+-- TOTP second factor
+-- Secrets are AES-GCM encrypted by auth-service; recovery codes are HMAC digests
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    -- NULL until the user confirms enrollment with a first code
+    confirmed_at TIMESTAMPTZ,
+    -- Last accepted time step, so a code cannot be replayed
+    last_used_counter BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+-- Set when the session passes TOTP or a recovery code; adds "mfa" to amr
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS mfa_verified_at TIMESTAMPTZ;
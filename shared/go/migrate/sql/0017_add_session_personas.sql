@@ -0,0 +1,15 @@
+-- Active persona per session for users registered in both apps
+-- 'client' or 'driver'; embedded in access tokens as the persona claim
+
+ALTER TABLE sessions
+ADD COLUMN IF NOT EXISTS persona VARCHAR(10) NOT NULL DEFAULT 'client';
+
+-- Existing sessions of driver-only accounts can only be driver sessions
+UPDATE sessions s
+SET persona = 'driver'
+FROM users u
+WHERE s.user_id = u.id AND u.user_type = 'driver' AND s.persona <> 'driver';
+
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_persona_check;
+ALTER TABLE sessions
+ADD CONSTRAINT sessions_persona_check CHECK (persona IN ('client', 'driver'));
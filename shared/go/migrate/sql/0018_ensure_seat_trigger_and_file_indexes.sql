@@ -0,0 +1,13 @@
+-- Re-creates what 0003 and 0005 add without IF NOT EXISTS, so databases
+-- that had those files applied by hand and were baselined past them end up
+-- with the same objects as fresh ones
+
+DROP TRIGGER IF EXISTS update_seat_config_timestamp ON vehicle_seat_configurations;
+CREATE TRIGGER update_seat_config_timestamp
+    BEFORE UPDATE ON vehicle_seat_configurations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_seat_config_timestamp();
+
+CREATE INDEX IF NOT EXISTS idx_uploaded_files_user ON uploaded_files(user_id);
+CREATE INDEX IF NOT EXISTS idx_uploaded_files_bucket ON uploaded_files(bucket);
+CREATE INDEX IF NOT EXISTS idx_uploaded_files_deleted ON uploaded_files(is_deleted) WHERE NOT is_deleted;
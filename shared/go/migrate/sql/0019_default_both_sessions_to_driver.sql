@@ -0,0 +1,10 @@
+-- Sessions of users registered in both apps that predate personas were
+-- given 'client' by 0017, but their access tokens carry no persona claim
+-- and resolve to 'driver' (auth.DefaultPersona). Align the rows so a token
+-- refresh does not move those sessions to the other app.
+
+UPDATE sessions s
+SET persona = 'driver'
+FROM users u, schema_migrations m
+WHERE s.user_id = u.id AND u.user_type = 'both' AND s.persona <> 'driver'
+  AND m.version = 17 AND s.created_at < m.applied_at;
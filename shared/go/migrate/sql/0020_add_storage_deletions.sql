@@ -0,0 +1,13 @@
+-- Stored files of purged accounts, waiting to be removed from object storage
+-- Rows are deleted once storage-service confirms; failures are retried later
+
+CREATE TABLE IF NOT EXISTS storage_deletions (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_deletions_next_attempt ON storage_deletions(next_attempt_at);
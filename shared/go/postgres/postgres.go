@@ -0,0 +1,50 @@
+// Package postgres opens the pgx connection pools the services share.
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/margwa/shared/go/tracing"
+)
+
+// Config describes a pool. Zero MaxConns and MinConns keep pgx's defaults.
+type Config struct {
+	URL      string
+	MaxConns int32
+	MinConns int32
+}
+
+// Connect creates a pool and pings the database so a bad URL or an
+// unreachable server fails at startup instead of on the first request.
+// Queries are traced when tracing.Setup has installed a provider.
+func Connect(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is not defined")
+	}
+
+	config, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database URL: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		config.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		config.MinConns = cfg.MinConns
+	}
+	config.ConnConfig.Tracer = tracing.QueryTracer()
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,39 @@
+// Package redisclient creates go-redis clients from REDIS_URL-style URLs.
+package redisclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// New creates a client without contacting the server. Use it where Redis is
+// optional and commands are expected to fail while it is down. Commands are
+// traced when tracing.Setup has installed a provider.
+func New(redisURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to instrument Redis client: %w", err)
+	}
+	return client, nil
+}
+
+// Connect creates a client and pings the server
+func Connect(ctx context.Context, redisURL string) (*redis.Client, error) {
+	client, err := New(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to connect to Redis: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,44 @@
+// Package response builds the JSON envelope every service responds with:
+//
+//	{"success": true, "data": ..., "message": "...", "timestamp": "..."}
+//	{"success": false, "error": {"code": "...", "message": "...", "details": ...}, "timestamp": "..."}
+package response
+
+import "time"
+
+// Body is the response envelope
+type Body struct {
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Error     *ErrorData  `json:"error,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// ErrorData carries a machine-readable code and optional details
+type ErrorData struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func Success(data interface{}, message string) Body {
+	return Body{
+		Success:   true,
+		Data:      data,
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
+
+func Error(code, message string, details interface{}) Body {
+	return Body{
+		Success: false,
+		Error: &ErrorData{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,180 @@
+// Package server runs a service's HTTP server until SIGINT or SIGTERM and
+// then shuts it down in order: readiness fails, the listener closes after a
+// drain delay, in-flight requests finish, and background workers stop.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config holds the listener settings. Zero durations use the defaults of
+// the same name below, except DrainDelay, which may be zero.
+type Config struct {
+	Port              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// DrainDelay is how long readiness reports draining before the listener
+	// closes, so load balancers stop routing here first
+	DrainDelay time.Duration
+
+	// ShutdownTimeout bounds waiting for in-flight requests and then for
+	// background workers and shutdown hooks
+	ShutdownTimeout time.Duration
+}
+
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 15 * time.Second
+	DefaultWriteTimeout      = 15 * time.Second
+	DefaultIdleTimeout       = 60 * time.Second
+	DefaultShutdownTimeout   = 10 * time.Second
+)
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Server is an http.Server with signal handling and shutdown hooks
+type Server struct {
+	cfg   Config
+	http  *http.Server
+	drain []func()
+	hooks []hook
+
+	// Background workers run with ctx, which stop cancels
+	ctx     context.Context
+	stop    context.CancelFunc
+	workers sync.WaitGroup
+}
+
+func New(handler http.Handler, cfg Config) *Server {
+	if cfg.ReadHeaderTimeout == 0 {
+		cfg.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = DefaultReadTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:              ":" + cfg.Port,
+			Handler:           handler,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		ctx:  ctx,
+		stop: stop,
+	}
+}
+
+// OnDrain registers fn to run as soon as a shutdown signal arrives, before
+// the drain delay. health.Checker.Drain belongs here.
+func (s *Server) OnDrain(fn func()) {
+	s.drain = append(s.drain, fn)
+}
+
+// OnShutdown registers fn to run after the HTTP server has stopped and
+// background workers have returned, for flushing buffers and closing
+// clients. Hooks run in reverse order of registration and share the
+// remaining shutdown timeout.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, hook{name: name, fn: fn})
+}
+
+// Go runs a background worker. Its context is cancelled once in-flight
+// requests have finished, and shutdown waits for it to return.
+func (s *Server) Go(fn func(ctx context.Context)) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn(s.ctx)
+	}()
+}
+
+// Run serves until SIGINT or SIGTERM, then shuts down. It returns an error
+// when the port cannot be bound, the server fails, or shutdown does not
+// complete within ShutdownTimeout.
+func (s *Server) Run() error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		s.stop()
+		return err
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(ln)
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serveErr:
+		s.stop()
+		return err
+	case sig := <-quit:
+		log.Printf("Received %s, shutting down server...", sig)
+	}
+
+	// Fail readiness first so load balancers stop routing here, then close
+	for _, fn := range s.drain {
+		fn()
+	}
+	time.Sleep(s.cfg.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.http.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+
+	s.stop()
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("background workers did not stop in time"))
+	}
+
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		if err := s.hooks[i].fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
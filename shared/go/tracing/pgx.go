@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer returns a pgx tracer that records a client span per query,
+// named after the statement ("SELECT users", "UPDATE payments"). The SQL
+// text is recorded; arguments are not, as they hold phone numbers, codes
+// and tokens.
+func QueryTracer() pgx.QueryTracer {
+	return queryTracer{tracer: otel.Tracer("github.com/margwa/shared/go/tracing")}
+}
+
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation, table := statement(data.SQL)
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.statement", data.SQL),
+	}
+	if table != "" {
+		attrs = append(attrs, attribute.String("db.sql.table", table))
+	}
+	if cfg := conn.Config(); cfg != nil {
+		attrs = append(attrs, attribute.String("db.name", cfg.Database))
+	}
+
+	ctx, _ = t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	// QueryRow reports a missing row as an error; that is an answer, not a failure
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+var tablePattern = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+([a-z_][a-z0-9_.]*)`)
+
+// statement returns the SQL verb and the first table the statement names
+func statement(sql string) (operation, table string) {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY", ""
+	}
+	operation = strings.ToUpper(fields[0])
+	if m := tablePattern.FindStringSubmatch(sql); m != nil {
+		table = m[1]
+	}
+	return operation, table
+}
@@ -0,0 +1,107 @@
+// Package tracing sets up OpenTelemetry tracing for the Go services: the
+// tracer provider and exporter, gin server spans, pgx query spans and
+// outbound HTTP client spans. Trace context travels between services in the
+// W3C traceparent header.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Config selects the span exporter. The OTLP exporter is further configured
+// by the standard OTEL_EXPORTER_OTLP_* variables (endpoint, headers,
+// protocol is HTTP/protobuf).
+type Config struct {
+	// Exporter is "otlp", "stdout" or "none"
+	Exporter string
+	// SampleRatio is the fraction of new traces recorded; requests that
+	// arrive with a sampled traceparent are always recorded
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagator. The returned function flushes buffered spans and must be
+// called before the process exits. With the "none" exporter spans are not
+// recorded, but incoming traceparent headers are still passed on.
+func Setup(ctx context.Context, service string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", service)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Middleware starts a server span for every request, named after the route
+// template and continuing the caller's trace. Health checks, probes and
+// metric scrapes are not traced.
+func Middleware(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/health", "/livez", "/readyz", "/metrics":
+			return false
+		}
+		return true
+	}))
+}
+
+// Context returns a context carrying the request's span for queries and
+// outbound calls made while handling c. Like context.Background, it is not
+// canceled when the client disconnects, so writes that must complete still
+// do.
+func Context(c *gin.Context) context.Context {
+	return context.WithoutCancel(c.Request.Context())
+}
+
+// Transport wraps http.DefaultTransport so outbound requests get client
+// spans and a traceparent header. Requests must be built with a context
+// (http.NewRequestWithContext) for the span to join the caller's trace.
+func Transport() http.RoundTripper {
+	return otelhttp.NewTransport(http.DefaultTransport)
+}
@@ -0,0 +1,177 @@
+import { createHmac, createPublicKey, timingSafeEqual, verify, JsonWebKey, KeyObject } from 'crypto';
+import type { JWTPayload } from '../types';
+
+// Verifies access tokens the same way the Go services do (shared/go/auth):
+// RS256 and EdDSA tokens against auth-service's JWKS, looked up by kid, and
+// HS256 tokens against JWT_SECRET while JWT_ACCEPT_HS256 is true.
+
+const JWKS_TTL_MS = 10 * 60 * 1000;
+// JWKS_MIN_REFRESH_MS stops tokens with unknown kids from hammering
+// auth-service, and spaces out retries while it is failing
+const JWKS_MIN_REFRESH_MS = 30 * 1000;
+const JWKS_FETCH_TIMEOUT_MS = 5 * 1000;
+
+export class TokenError extends Error {
+    constructor(name: 'TokenExpiredError' | 'JsonWebTokenError', message: string) {
+        super(message);
+        this.name = name;
+    }
+}
+
+export interface TokenVerifierOptions {
+    jwksUrl?: string;
+    // Accept HS256 tokens signed with this secret; leave empty to refuse them
+    hmacSecret?: string;
+}
+
+// JwksCache keeps the last good key set. A stale set is served while the
+// refresh runs in the background; only an unknown kid waits for one.
+class JwksCache {
+    private keys = new Map<string, KeyObject>();
+    private fetchedAt = 0;
+    private attemptedAt = 0;
+    private inflight?: Promise<void>;
+
+    constructor(private readonly url: string) {}
+
+    async key(kid: string): Promise<KeyObject> {
+        const now = Date.now();
+        const key = this.keys.get(kid);
+        const stale = now - this.fetchedAt >= JWKS_TTL_MS;
+        const throttled = now - this.attemptedAt < JWKS_MIN_REFRESH_MS;
+
+        if (key) {
+            if (stale && !throttled) {
+                this.refresh().catch(() => undefined);
+            }
+            return key;
+        }
+        if (throttled) {
+            throw new TokenError('JsonWebTokenError', `unknown signing key "${kid}"`);
+        }
+
+        await this.refresh();
+        const fresh = this.keys.get(kid);
+        if (!fresh) {
+            throw new TokenError('JsonWebTokenError', `unknown signing key "${kid}"`);
+        }
+        return fresh;
+    }
+
+    // refresh shares one fetch between concurrent callers
+    private refresh(): Promise<void> {
+        if (!this.inflight) {
+            this.inflight = this.load().finally(() => {
+                this.attemptedAt = Date.now();
+                this.inflight = undefined;
+            });
+        }
+        return this.inflight;
+    }
+
+    private async load(): Promise<void> {
+        const res = await fetch(this.url, { signal: AbortSignal.timeout(JWKS_FETCH_TIMEOUT_MS) });
+        if (!res.ok) {
+            throw new Error(`JWKS endpoint returned status ${res.status}`);
+        }
+        const doc = (await res.json()) as { keys?: Array<JsonWebKey & { kid?: string }> };
+
+        const keys = new Map<string, KeyObject>();
+        for (const jwk of doc.keys ?? []) {
+            if (!jwk.kid || !(jwk.kty === 'RSA' || (jwk.kty === 'OKP' && jwk.crv === 'Ed25519'))) {
+                continue;
+            }
+            try {
+                keys.set(jwk.kid, createPublicKey({ key: jwk, format: 'jwk' }));
+            } catch {
+                // Skip malformed keys, as the Go cache does
+            }
+        }
+        if (keys.size === 0) {
+            throw new Error('JWKS document has no usable keys');
+        }
+
+        this.keys = keys;
+        this.fetchedAt = Date.now();
+    }
+}
+
+function decodeSegment(segment: string): any {
+    try {
+        return JSON.parse(Buffer.from(segment, 'base64url').toString('utf8'));
+    } catch {
+        throw new TokenError('JsonWebTokenError', 'malformed token');
+    }
+}
+
+export function createTokenVerifier(options: TokenVerifierOptions) {
+    const jwks = options.jwksUrl ? new JwksCache(options.jwksUrl) : undefined;
+
+    return async (token: string): Promise<JWTPayload> => {
+        const parts = token.split('.');
+        if (parts.length !== 3) {
+            throw new TokenError('JsonWebTokenError', 'malformed token');
+        }
+        const [encodedHeader, encodedPayload, encodedSignature] = parts;
+        const header = decodeSegment(encodedHeader);
+        const signed = Buffer.from(`${encodedHeader}.${encodedPayload}`);
+        const signature = Buffer.from(encodedSignature, 'base64url');
+
+        let valid: boolean;
+        switch (header.alg) {
+            case 'HS256': {
+                if (!options.hmacSecret) {
+                    throw new TokenError('JsonWebTokenError', 'HS256 tokens are not accepted');
+                }
+                const expected = createHmac('sha256', options.hmacSecret).update(signed).digest();
+                valid = expected.length === signature.length && timingSafeEqual(expected, signature);
+                break;
+            }
+            case 'RS256':
+            case 'EdDSA': {
+                if (!jwks) {
+                    throw new TokenError('JsonWebTokenError', 'JWKS_URL is not configured');
+                }
+                const key = await jwks.key(typeof header.kid === 'string' ? header.kid : '');
+                if (header.alg === 'RS256' && key.asymmetricKeyType === 'rsa') {
+                    valid = verify('sha256', signed, key, signature);
+                } else if (header.alg === 'EdDSA' && key.asymmetricKeyType === 'ed25519') {
+                    valid = verify(null, signed, key, signature);
+                } else {
+                    valid = false;
+                }
+                break;
+            }
+            default:
+                throw new TokenError('JsonWebTokenError', `unexpected signing method: ${header.alg}`);
+        }
+        if (!valid) {
+            throw new TokenError('JsonWebTokenError', 'invalid signature');
+        }
+
+        const claims = decodeSegment(encodedPayload) as JWTPayload & { nbf?: number };
+        const now = Date.now() / 1000;
+        if (typeof claims.exp === 'number' && now >= claims.exp) {
+            throw new TokenError('TokenExpiredError', 'token expired');
+        }
+        if (typeof claims.nbf === 'number' && now < claims.nbf) {
+            throw new TokenError('JsonWebTokenError', 'token not yet valid');
+        }
+        return claims;
+    };
+}
+
+let defaultVerifier: ReturnType<typeof createTokenVerifier> | undefined;
+
+// verifyAccessToken checks a token with JWKS_URL, JWT_SECRET and
+// JWT_ACCEPT_HS256 (default true) from the environment, read on first use
+export function verifyAccessToken(token: string): Promise<JWTPayload> {
+    if (!defaultVerifier) {
+        const acceptHS256 = (process.env.JWT_ACCEPT_HS256 ?? 'true') === 'true';
+        defaultVerifier = createTokenVerifier({
+            jwksUrl: process.env.JWKS_URL || undefined,
+            hmacSecret: acceptHS256 ? process.env.JWT_SECRET : undefined,
+        });
+    }
+    return defaultVerifier(token);
+}